@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/gob"
+	"errors"
 	"fmt"
 	"github.com/boltdb/bolt"
 	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/common"
@@ -41,6 +42,9 @@ func GoBackgrounds() {
 		// there is still 7*24 hours for renewal
 		if common.Expired(ticObj.ExpireAt.Add(7 * 24 * time.Hour)) {
 			// really delete
+			if err := service.RequestRemoveSubDomains(tx, ticObj.Ticket); err != nil {
+				log.Warn("clean ticket: %v", err)
+			}
 			return true, []string{ticObj.ChatIdentifier}
 		}
 		// we only sync at the first 3 hours because the sync costs a lot
@@ -67,6 +71,9 @@ func GoBackgrounds() {
 			}
 			if now.Sub(server.LastSeen) >= 35*24*time.Hour {
 				log.Info("remove server ticket %v because of long time no see", server.Name)
+				if err := service.RequestRemoveSubDomains(tx, ticObj.Ticket); err != nil {
+					log.Warn("clean ticket: %v", err)
+				}
 				return true, []string{ticObj.ChatIdentifier}
 			}
 		}
@@ -88,11 +95,101 @@ func GoBackgrounds() {
 			return false, nil
 		}
 		if now.Sub(server.LastSeen) >= 35*24*time.Hour {
+			if err := service.RequestRemoveSubDomains(tx, server.Ticket); err != nil {
+				log.Warn("clean server: %v", err)
+			}
 			return true, []string{ticObj.ChatIdentifier}
 		}
 		return false, nil
 	})()
 
+	// remove DNS records of the subdomains of revoked or purged servers
+	go TickUpdateBackground(model.BucketSubDomain, 10*time.Minute, func(b []byte, now time.Time) (todo func(wtx *bolt.Tx, b []byte) []byte) {
+		var sub model.SubDomain
+		if err := jsoniter.Unmarshal(b, &sub); err != nil {
+			return nil
+		}
+		if !sub.Removing {
+			return nil
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+		defer cancel()
+		// the subdomain is re-checked because the snapshot may be stale
+		err := service.RemoveSubDomainRecords(ctx, sub.Domain)
+		if errors.Is(err, service.ErrSubDomainInUse) {
+			log.Info("keep DNS records of %v: %v", sub.Domain, err)
+			return nil
+		}
+		return func(wtx *bolt.Tx, b []byte) []byte {
+			if err == nil {
+				log.Info("removed DNS records of %v (%v)", sub.Domain, sub.ServerName)
+				if e := service.UntrackSubDomain(wtx, sub.Domain, sub.AssignedAt); e != nil {
+					log.Warn("%v", e)
+				}
+				return nil
+			}
+			log.Warn("%v", err)
+			var cur model.SubDomain
+			if e := jsoniter.Unmarshal(b, &cur); e != nil || !cur.AssignedAt.Equal(sub.AssignedAt) || !cur.Removing {
+				// it has been assigned again
+				return nil
+			}
+			cur.RemoveAttempts++
+			cur.LastError = err.Error()
+			if cur.RemoveAttempts == model.MaxSubDomainRemoveAttempts {
+				if e := service.AddFeedSubDomain(wtx, cur, service.ServerActionDNSRemovalFailed); e != nil {
+					log.Warn("AddFeedSubDomain: %v", e)
+				}
+			}
+			b, e := jsoniter.Marshal(cur)
+			if e != nil {
+				return nil
+			}
+			return b
+		}
+	})()
+
+	// remove usages of tickets that have been removed
+	go ExpireCleanBackground(model.BucketUsage, 1*time.Hour, func(tx *bolt.Tx, b []byte, now time.Time) (expired bool, chatToSync []string) {
+		var usage model.TicketUsage
+		if err := jsoniter.Unmarshal(b, &usage); err != nil {
+			return true, nil
+		}
+		return service.IsUsageOrphan(tx, usage), nil
+	})()
+
+	// restore the user tickets cut off at the reset day
+	go TickUpdateBackground(model.BucketUsage, 10*time.Minute, func(b []byte, now time.Time) (todo func(wtx *bolt.Tx, b []byte) []byte) {
+		var usage model.TicketUsage
+		if err := jsoniter.Unmarshal(b, &usage); err != nil {
+			return nil
+		}
+		if !usage.CutOff {
+			return nil
+		}
+		return func(wtx *bolt.Tx, b []byte) []byte {
+			var usage model.TicketUsage
+			if err := jsoniter.Unmarshal(b, &usage); err != nil {
+				return nil
+			}
+			if !service.UpdateQuotaState(wtx, &usage, now) {
+				return nil
+			}
+			// asynchronously invoke sync to make sure it will happen after updating
+			time.AfterFunc(1*time.Second, func() {
+				// do not pass in tx here due to async
+				if e := service.ReqSyncPassagesByChatIdentifier(nil, usage.ChatIdentifier, true); e != nil {
+					log.Warn("ReqSyncPassagesByChatIdentifier: %v", e)
+				}
+			})
+			b, err := jsoniter.Marshal(usage)
+			if err != nil {
+				return nil
+			}
+			return b
+		}
+	})()
+
 	// ping at intervals
 	go TickUpdateBackground(model.BucketServer, 1*time.Minute, func(b []byte, now time.Time) (todo func(wtx *bolt.Tx, b []byte) []byte) {
 		var server model.Server
@@ -105,7 +202,10 @@ func GoBackgrounds() {
 		}
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
-		if resp, err := service.Ping(ctx, server); err != nil {
+		begin := time.Now()
+		resp, err := service.Ping(ctx, server)
+		service.ObservePing(server, time.Since(begin), err)
+		if err != nil {
 			log.Info("Ping server %v: %v", strconv.Quote(server.Name), err)
 			todo = func(wtx *bolt.Tx, b []byte) []byte {
 				var server model.Server
@@ -152,6 +252,11 @@ func GoBackgrounds() {
 				}
 				server.FailureCount = 0
 				server.LastSeen = time.Now()
+				if len(resp.PassageUsages) > 0 {
+					if err := service.AddPassageUsages(wtx, server, resp.PassageUsages); err != nil {
+						log.Warn("%v", err)
+					}
+				}
 				if server.BandwidthLimit.IsTimeToReset() {
 					if server.BandwidthLimit.Exhausted() {
 						_ = service.AddFeedServer(wtx, server, service.ServerActionBandwidthReset)
@@ -188,8 +293,74 @@ func GoBackgrounds() {
 		return todo
 	})()
 
+	// measure the RTTs from relays to their upstream servers
+	go TickUpdateBackground(model.BucketServer, 10*time.Minute, func(b []byte, now time.Time) (todo func(wtx *bolt.Tx, b []byte) []byte) {
+		var relay model.Server
+		if err := jsoniter.Unmarshal(b, &relay); err != nil {
+			return nil
+		}
+		if relay.FailureCount >= model.MaxFailureCount {
+			return nil
+		}
+		if tic, err := service.GetValidTicketObj(nil, relay.Ticket); err != nil || tic.Type != model.TicketTypeRelay {
+			return nil
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		probe, err := service.ProbeRelay(ctx, relay)
+		if err != nil {
+			log.Debug("%v", err)
+			return nil
+		}
+		return func(wtx *bolt.Tx, b []byte) []byte {
+			if err := service.SaveRelayProbe(wtx, *probe); err != nil {
+				log.Warn("%v", err)
+			}
+			// the server itself is not changed
+			return nil
+		}
+	})()
+
+	// remove probes of relays that have been removed
+	go ExpireCleanBackground(model.BucketProbe, 1*time.Hour, func(tx *bolt.Tx, b []byte, now time.Time) (expired bool, chatToSync []string) {
+		var probe model.RelayProbe
+		if err := jsoniter.Unmarshal(b, &probe); err != nil {
+			return true, nil
+		}
+		_, err := service.GetServerByTicket(tx, probe.Ticket)
+		return err != nil, nil
+	})()
+
+	// issue and renew the certificates of the subdomains of TLS servers
+	go TickUpdateBackground(model.BucketServer, 12*time.Hour, func(b []byte, now time.Time) (todo func(wtx *bolt.Tx, b []byte) []byte) {
+		if !service.ACMEEnabled() {
+			return nil
+		}
+		var server model.Server
+		if err := jsoniter.Unmarshal(b, &server); err != nil {
+			return nil
+		}
+		if server.FailureCount >= model.MaxFailureCount {
+			return nil
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+		if err := service.ServeCertificate(ctx, server, false); err != nil {
+			log.Warn("%v", err)
+		}
+		return nil
+	})()
+
+	// remove expired certificates
+	go ExpireCleanBackground(model.BucketCertificate, 1*time.Hour, func(tx *bolt.Tx, b []byte, now time.Time) (expired bool, chatToSync []string) {
+		var cert model.Certificate
+		if err := jsoniter.Unmarshal(b, &cert); err != nil {
+			return true, nil
+		}
+		return now.After(cert.NotAfter), nil
+	})()
+
 	// remove expired feeds
-	// FIXME: remove DNS record after revoking
 	go TickUpdateBackground(model.BucketFeed, 1*time.Hour, func(b []byte, now time.Time) (todo func(wtx *bolt.Tx, b []byte) []byte) {
 		return func(wtx *bolt.Tx, b []byte) []byte {
 			var feed model.ChatFeed
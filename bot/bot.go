@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/common"
 	tb "gopkg.in/tucnak/telebot.v2"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -20,38 +21,87 @@ func RegisterCommands(command string, f CommandHandler) {
 	GlobalCommandMapper[command] = f
 }
 
-func New(token string, poller *tb.LongPoller) (*Bot, error) {
-	if poller == nil {
+// New creates the bot. The poller defaults to a tb.LongPoller, and apiURL defaults to the official Bot API server.
+func New(token string, apiURL string, poller tb.Poller) (*Bot, error) {
+	longPolling := poller == nil
+	if longPolling {
 		poller = &tb.LongPoller{Timeout: 15 * time.Second}
 	}
 	b, err := tb.NewBot(tb.Settings{
+		URL:    apiURL,
 		Token:  token,
 		Poller: poller,
 	})
 	if err != nil {
 		return nil, err
 	}
+	if longPolling {
+		// getUpdates does not work if a webhook was set
+		if err = b.RemoveWebhook(); err != nil {
+			return nil, fmt.Errorf("RemoveWebhook: %w", err)
+		}
+	} else if webhook, ok := poller.(*Webhook); ok {
+		// set it here rather than in Poll, otherwise the bot would receive nothing silently if it fails
+		if err = webhook.set(b); err != nil {
+			return nil, fmt.Errorf("setWebhook: %w", err)
+		}
+	}
 	bot := &Bot{
 		Bot: b,
 	}
-	b.Handle(tb.OnChannelPost, func(m *tb.Message) {
+	handle := func(m *tb.Message) {
 		if !strings.HasPrefix(m.Text, "/") || len(m.Text) <= 1 {
 			return
 		}
 		text := strings.TrimPrefix(m.Text, "/")
 		fields := strings.Fields(text)
-		if handler, ok := GlobalCommandMapper[fields[0]]; ok {
-			if !m.FromChannel() || m.Signature != "" {
-				_, _ = b.Reply(m, "Please use me from an anonymous channel.")
+		if len(fields) == 0 {
+			return
+		}
+		command := fields[0]
+		// commands in groups may be suffixed with the username of the bot
+		if i := strings.Index(command, "@"); i >= 0 {
+			if !strings.EqualFold(command[i+1:], b.Me.Username) {
+				return
+			}
+			command = command[:i]
+		}
+		if handler, ok := GlobalCommandMapper[command]; ok {
+			// signatures of channel posts can be changed by any admin, thus they cannot identify members
+			anonymousChannel := m.FromChannel() && m.Signature == ""
+			if !anonymousChannel && !m.FromGroup() {
+				_, _ = b.Reply(m, "Please use me from an anonymous channel or a group.")
 				return
 			}
 			handler(bot, m, fields[1:])
 		}
-	})
-	b.Start()
+	}
+	b.Handle(tb.OnChannelPost, handle)
+	b.Handle(tb.OnText, handle)
 	return bot, nil
 }
 
+// Start starts polling updates. It blocks until the bot is stopped.
+func (b *Bot) Start() {
+	b.Bot.Start()
+}
+
+// Notify posts the text to the chat silently.
+func (b *Bot) Notify(chatID int64, text string) error {
+	_, err := b.Bot.Send(&tb.Chat{ID: chatID}, text, tb.Silent, tb.NoPreview)
+	return err
+}
+
+// Member returns the identifier of the author of the message, which is the Telegram user ID in groups.
+// Empty means anonymous, such as posts of channels and messages of anonymous admins of groups, because
+// Telegram does not tell who sent them.
+func (b *Bot) Member(m *tb.Message) string {
+	if !m.FromGroup() || m.Sender == nil || m.SenderChat != nil {
+		return ""
+	}
+	return strconv.FormatInt(m.Sender.ID, 10)
+}
+
 func (b *Bot) ChatIdentifier(c *tb.Chat) string {
 	strChatID := fmt.Sprintf("%v", c.ID)
 	return common.StringToUUID5(strChatID)
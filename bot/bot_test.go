@@ -0,0 +1,24 @@
+package bot
+
+import (
+	"testing"
+
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+func TestMember(t *testing.T) {
+	group := &tb.Chat{ID: -1002, Type: tb.ChatSuperGroup}
+	for _, c := range []struct {
+		name string
+		m    *tb.Message
+		want string
+	}{
+		{"group", &tb.Message{Chat: group, Sender: &tb.User{ID: 42}}, "42"},
+		{"anonymous admin", &tb.Message{Chat: group, Sender: &tb.User{ID: 1087968824}, SenderChat: group}, ""},
+		{"channel", &tb.Message{Chat: &tb.Chat{ID: -1001, Type: tb.ChatChannel}, Signature: "owner"}, ""},
+	} {
+		if got := (&Bot{}).Member(c.m); got != c.want {
+			t.Errorf("%v: got %q, want %q", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package command_handler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/bot"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/model"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/pkg/log"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/service"
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+func init() {
+	bot.RegisterCommands("notify", Notify)
+}
+
+func notifyUsage() string {
+	events := make([]string, 0, len(model.NotificationEvents))
+	for _, e := range model.NotificationEvents {
+		events = append(events, string(e))
+	}
+	return "Invalid notify params. Format:\n" +
+		"/notify on|off\n" +
+		"/notify mute|unmute <event>...\n" +
+		"Events: " + strings.Join(events, ", ")
+}
+
+func Notify(b *bot.Bot, m *tb.Message, params []string) {
+	chatIdentifier := b.ChatIdentifier(m.Chat)
+	chat, err := service.GetChat(nil, chatIdentifier)
+	if err != nil {
+		b.Bot.Reply(m, err.Error(), tb.Silent, tb.NoPreview)
+		return
+	}
+	if len(params) < 1 {
+		b.Bot.Reply(m, notifySetting(chat), tb.Silent, tb.NoPreview)
+		return
+	}
+	if !requireRole(b, m, model.RoleOperator) {
+		return
+	}
+	switch params[0] {
+	case "on":
+		chat.Notify = true
+		chat.TelegramChatID = m.Chat.ID
+	case "off":
+		chat.Notify = false
+	case "mute", "unmute":
+		if len(params) < 2 {
+			b.Bot.Reply(m, notifyUsage(), tb.Silent, tb.NoPreview)
+			return
+		}
+		for _, p := range params[1:] {
+			event := model.NotificationEvent(p)
+			if !event.IsValid() {
+				b.Bot.Reply(m, notifyUsage(), tb.Silent, tb.NoPreview)
+				return
+			}
+			// remove it first to avoid duplicates
+			var muted []model.NotificationEvent
+			for _, e := range chat.MutedEvents {
+				if e != event {
+					muted = append(muted, e)
+				}
+			}
+			if params[0] == "mute" {
+				muted = append(muted, event)
+			}
+			chat.MutedEvents = muted
+		}
+	default:
+		b.Bot.Reply(m, notifyUsage(), tb.Silent, tb.NoPreview)
+		return
+	}
+	log.Info("Notify: chatIdentifier: %v, params: %v", chatIdentifier, params)
+	if err = service.SaveChat(nil, chat); err != nil {
+		b.Bot.Reply(m, err.Error(), tb.Silent, tb.NoPreview)
+		return
+	}
+	b.Bot.Reply(m, notifySetting(chat), tb.Silent, tb.NoPreview)
+}
+
+func notifySetting(chat model.Chat) string {
+	state := "off"
+	if chat.Notify {
+		state = "on"
+	}
+	muted := "none"
+	if len(chat.MutedEvents) > 0 {
+		events := make([]string, 0, len(chat.MutedEvents))
+		for _, e := range chat.MutedEvents {
+			events = append(events, string(e))
+		}
+		muted = strings.Join(events, ", ")
+	}
+	return fmt.Sprintf("Notifications: %v\nMuted: %v", state, muted)
+}
@@ -20,7 +20,7 @@ func Revoke(b *bot.Bot, m *tb.Message, params []string) {
 
 	log.Info("Revoke: chatIdentifier: %v, text: %v", chatIdentifier, params[0])
 	// m.Text should be a random string for verification
-	if err := service.RevokeTicket(nil, params[0], chatIdentifier); err != nil {
+	if err := service.RevokeTicketAs(nil, params[0], chatIdentifier, b.Member(m)); err != nil {
 		b.Bot.Reply(m, err.Error(), tb.Silent, tb.NoPreview)
 	} else {
 		b.Bot.Reply(m, "Revoked.", tb.Silent, tb.NoPreview)
@@ -0,0 +1,133 @@
+package command_handler
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/bot"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/model"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/pkg/log"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/service"
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+func init() {
+	bot.RegisterCommands("role", Role)
+}
+
+const roleUsage = "Invalid role params. Format:\n" +
+	"/role\n" +
+	"/role claim\n" +
+	"/role owner|operator|member <user ID>\n" +
+	"Reply to a message with /role owner|operator|member to set the role of its sender.\n" +
+	"Roles are enforced once the group has an owner, which can be claimed by the creator or an admin of the group."
+
+// requireRole replies and returns false if the author of the message does not have the role.
+func requireRole(b *bot.Bot, m *tb.Message, role model.Role) bool {
+	chatIdentifier := b.ChatIdentifier(m.Chat)
+	r, err := service.GetRole(nil, chatIdentifier, b.Member(m))
+	if err != nil {
+		b.Bot.Reply(m, err.Error(), tb.Silent, tb.NoPreview)
+		return false
+	}
+	if r < role {
+		b.Bot.Reply(m, fmt.Sprintf("%v: %v is required.", model.ErrPermissionDenied, role), tb.Silent, tb.NoPreview)
+		return false
+	}
+	return true
+}
+
+func Role(b *bot.Bot, m *tb.Message, params []string) {
+	if !m.FromGroup() {
+		b.Bot.Reply(m, "Roles are only available in groups because Telegram does not tell who posts in a channel. "+
+			"Everyone who can post in the channel can do everything.", tb.Silent, tb.NoPreview)
+		return
+	}
+	chatIdentifier := b.ChatIdentifier(m.Chat)
+	member := b.Member(m)
+	if len(params) < 1 {
+		roles, err := service.GetChatRoles(nil, chatIdentifier)
+		if err != nil {
+			b.Bot.Reply(m, err.Error(), tb.Silent, tb.NoPreview)
+			return
+		}
+		b.Bot.Reply(m, roleList(roles, member), tb.Silent, tb.NoPreview)
+		return
+	}
+	var target string
+	var role model.Role
+	if params[0] == "claim" {
+		if !isGroupAdmin(b, m) {
+			b.Bot.Reply(m, fmt.Sprintf("%v: only the creator or admins of the group can claim the owner.", model.ErrPermissionDenied), tb.Silent, tb.NoPreview)
+			return
+		}
+		target, role = member, model.RoleOwner
+	} else {
+		var err error
+		if role, err = model.ParseRole(params[0]); err != nil {
+			b.Bot.Reply(m, roleUsage, tb.Silent, tb.NoPreview)
+			return
+		}
+		switch {
+		case len(params) >= 2:
+			if _, err = strconv.ParseInt(params[1], 10, 64); err != nil {
+				b.Bot.Reply(m, roleUsage, tb.Silent, tb.NoPreview)
+				return
+			}
+			target = params[1]
+		case m.ReplyTo != nil:
+			target = b.Member(m.ReplyTo)
+		default:
+			b.Bot.Reply(m, roleUsage, tb.Silent, tb.NoPreview)
+			return
+		}
+	}
+	log.Info("Role: chatIdentifier: %v, operator: %v, member: %v, role: %v", chatIdentifier, member, target, role)
+	roles, err := service.SetRole(nil, chatIdentifier, member, target, role)
+	if err != nil {
+		b.Bot.Reply(m, err.Error(), tb.Silent, tb.NoPreview)
+		return
+	}
+	b.Bot.Reply(m, roleList(roles, member), tb.Silent, tb.NoPreview)
+}
+
+// isGroupAdmin reports if the sender of the message is the creator or an admin of the group.
+func isGroupAdmin(b *bot.Bot, m *tb.Message) bool {
+	if b.Member(m) == "" {
+		return false
+	}
+	cm, err := b.Bot.ChatMemberOf(m.Chat, m.Sender)
+	if err != nil {
+		log.Warn("ChatMemberOf: %v", err)
+		return false
+	}
+	return cm.Role == tb.Creator || cm.Role == tb.Administrator
+}
+
+func roleList(roles model.ChatRoles, member string) string {
+	if !roles.Enforced() {
+		return "Roles are not enforced because the group has no owner. Claim it by /role claim as the creator or an admin of the group."
+	}
+	members := make([]string, 0, len(roles.Roles))
+	for m := range roles.Roles {
+		members = append(members, m)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if roles.Roles[members[i]] != roles.Roles[members[j]] {
+			return roles.Roles[members[i]] > roles.Roles[members[j]]
+		}
+		return members[i] < members[j]
+	})
+	var sb strings.Builder
+	for _, m := range members {
+		fmt.Fprintf(&sb, "%v: %v\n", roles.Roles[m], m)
+	}
+	you := member
+	if you == "" {
+		you = "anonymous"
+	}
+	fmt.Fprintf(&sb, "You (%v): %v", you, roles.GetRole(member))
+	return sb.String()
+}
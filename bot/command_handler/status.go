@@ -0,0 +1,121 @@
+package command_handler
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/bot"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/model"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/pkg/log"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/service"
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+// maxStatusLength is a bit less than the limit of the length of telegram messages.
+const maxStatusLength = 4000
+
+func init() {
+	bot.RegisterCommands("status", Status)
+}
+
+func Status(b *bot.Bot, m *tb.Message, params []string) {
+	chatIdentifier := b.ChatIdentifier(m.Chat)
+	statuses, err := service.GetServerStatuses(nil, chatIdentifier)
+	if err != nil {
+		log.Warn("Status: chatIdentifier: %v: %v", chatIdentifier, err)
+		b.Bot.Reply(m, err.Error(), tb.Silent, tb.NoPreview)
+		return
+	}
+	if len(statuses) == 0 {
+		b.Bot.Reply(m, "No server or relay in this chat.", tb.Silent, tb.NoPreview)
+		return
+	}
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 1, ' ', 0)
+	fmt.Fprintln(w, "Name\tType\tOn\tFail\tQuota\tReset")
+	for _, s := range statuses {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\n",
+			s.Name,
+			statusType(s.Type),
+			statusOnline(s),
+			s.FailureCount,
+			statusQuota(s.RemainingKiB),
+			statusReset(s),
+		)
+	}
+	w.Flush()
+	for _, chunk := range statusChunks(sb.String(), maxStatusLength) {
+		if _, err = b.Bot.Reply(m, chunk, tb.ModeHTML, tb.Silent, tb.NoPreview); err != nil {
+			log.Warn("Status: chatIdentifier: %v: %v", chatIdentifier, err)
+			return
+		}
+	}
+}
+
+// statusChunks splits the table into preformatted messages no longer than maxLength. The header is repeated in
+// every message, and too long lines are truncated.
+func statusChunks(table string, maxLength int) (chunks []string) {
+	const pre, endPre = "<pre>", "</pre>"
+	lines := strings.SplitAfter(table, "\n")
+	header := html.EscapeString(lines[0])
+	var sb strings.Builder
+	for _, line := range lines[1:] {
+		if line == "" {
+			continue
+		}
+		line = html.EscapeString(line)
+		if room := maxLength - len(pre) - len(header) - len(endPre) - 1; len(line) > room {
+			// do not cut an escaped character or a rune
+			line = strings.ToValidUTF8(line[:room], "")
+			if i := strings.LastIndex(line, "&"); i >= 0 && !strings.Contains(line[i:], ";") {
+				line = line[:i]
+			}
+			line += "\n"
+		}
+		if sb.Len() > 0 && len(pre)+len(header)+sb.Len()+len(line)+len(endPre) > maxLength {
+			chunks = append(chunks, pre+header+sb.String()+endPre)
+			sb.Reset()
+		}
+		sb.WriteString(line)
+	}
+	return append(chunks, pre+header+sb.String()+endPre)
+}
+
+func statusType(t model.TicketType) string {
+	switch t {
+	case model.TicketTypeServer:
+		return "server"
+	case model.TicketTypeRelay:
+		return "relay"
+	default:
+		return "-"
+	}
+}
+
+func statusOnline(s model.ServerStatus) string {
+	switch {
+	case !s.Online:
+		return "✗"
+	case s.Exhausted:
+		return "⚠"
+	default:
+		return "✓"
+	}
+}
+
+// statusQuota formats the remaining bandwidth in GiB. "∞" means no limit.
+func statusQuota(remainingKiB *int64) string {
+	if remainingKiB == nil {
+		return "∞"
+	}
+	return fmt.Sprintf("%.1fG", float64(*remainingKiB)/1024/1024)
+}
+
+func statusReset(s model.ServerStatus) string {
+	if s.NextResetAt == nil {
+		return "-"
+	}
+	return s.NextResetAt.Format("01-02")
+}
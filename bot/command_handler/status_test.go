@@ -0,0 +1,47 @@
+package command_handler
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var entities = regexp.MustCompile(`&(amp|lt|gt|quot|#39);`)
+
+func TestStatusChunks(t *testing.T) {
+	header := "Name Type\n"
+	var sb strings.Builder
+	sb.WriteString(header)
+	for i := 0; i < 100; i++ {
+		sb.WriteString("server<&> relay\n")
+	}
+	// a line too long to fit in a message
+	sb.WriteString(strings.Repeat("&", 100) + "\n")
+	chunks := statusChunks(sb.String(), 200)
+	if len(chunks) < 2 {
+		t.Fatalf("expected multiple chunks, got %v", len(chunks))
+	}
+	rows := 0
+	for _, chunk := range chunks {
+		if len(chunk) > 200 {
+			t.Fatalf("chunk is too long: %v", len(chunk))
+		}
+		if !strings.HasPrefix(chunk, "<pre>"+header) || !strings.HasSuffix(chunk, "</pre>") {
+			t.Fatalf("unexpected chunk: %q", chunk)
+		}
+		if strings.Contains(entities.ReplaceAllString(chunk, ""), "&") {
+			t.Fatalf("an escaped character is cut: %q", chunk)
+		}
+		rows += strings.Count(chunk, "server&lt;&amp;&gt; relay\n")
+	}
+	if rows != 100 {
+		t.Fatalf("expected 100 rows, got %v", rows)
+	}
+	if !strings.Contains(chunks[len(chunks)-1], "&amp;&amp;") {
+		t.Fatal("the long line should be truncated rather than dropped")
+	}
+
+	if chunks = statusChunks("Name Type\na b\n", 4000); len(chunks) != 1 || chunks[0] != "<pre>Name Type\na b\n</pre>" {
+		t.Fatalf("unexpected chunks: %q", chunks)
+	}
+}
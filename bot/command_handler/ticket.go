@@ -0,0 +1,109 @@
+package command_handler
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/bot"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/common"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/config"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/model"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/pkg/log"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/service"
+	gonanoid "github.com/matoous/go-nanoid"
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+func init() {
+	bot.RegisterCommands("ticket", Ticket)
+	bot.RegisterCommands("renew", Renew)
+}
+
+var ticketTypes = map[string]model.TicketType{
+	"user":   model.TicketTypeUser,
+	"server": model.TicketTypeServer,
+	"relay":  model.TicketTypeRelay,
+}
+
+func subscriptionURL(ticket string) string {
+	u := url.URL{
+		Scheme: "https",
+		Host:   config.GetConfig().Host,
+		Path:   path.Join("api", "ticket", ticket, "sub"),
+	}
+	return u.String()
+}
+
+func ticketReply(tic model.Ticket) string {
+	text := fmt.Sprintf("Ticket: %v", tic.Ticket)
+	if tic.Type == model.TicketTypeUser {
+		text += fmt.Sprintf("\nExpire at: %v\nSubscription: %v", tic.ExpireAt.Format("2006-01-02 15:04:05 MST"), subscriptionURL(tic.Ticket))
+	}
+	return text
+}
+
+// Ticket issues a ticket for the chat. The verification is skipped because the message in the chat proves the membership.
+func Ticket(b *bot.Bot, m *tb.Message, params []string) {
+	chatIdentifier := b.ChatIdentifier(m.Chat)
+	if len(params) < 1 {
+		b.Bot.Reply(m, "Invalid ticket params. Format:\n/ticket user|server|relay", tb.Silent, tb.NoPreview)
+		return
+	}
+	typ, ok := ticketTypes[params[0]]
+	if !ok {
+		b.Bot.Reply(m, "Invalid ticket params. Format:\n/ticket user|server|relay", tb.Silent, tb.NoPreview)
+		return
+	}
+	// issuing server and relay tickets is to change the servers of the chat
+	if typ != model.TicketTypeUser && !requireRole(b, m, model.RoleOperator) {
+		return
+	}
+	ticket, err := gonanoid.Generate(common.Alphabet, model.TicketLength)
+	if err != nil {
+		b.Bot.Reply(m, fmt.Sprintf("%v: try again please", err), tb.Silent, tb.NoPreview)
+		return
+	}
+	log.Info("Ticket: chatIdentifier: %v, type: %v", chatIdentifier, params[0])
+	tic, err := service.SaveTicket(nil, ticket, typ, chatIdentifier, b.Member(m))
+	if err != nil {
+		b.Bot.Reply(m, err.Error(), tb.Silent, tb.NoPreview)
+		return
+	}
+	if tic.Type == model.TicketTypeUser {
+		if err = service.ReqSyncPassagesByChatIdentifier(nil, chatIdentifier, true); err != nil {
+			log.Warn("ReqSyncPassagesByChatIdentifier: %v", err)
+		}
+	}
+	b.Bot.Reply(m, ticketReply(tic), tb.Silent, tb.NoPreview)
+}
+
+// Renew renews the user ticket of the chat.
+func Renew(b *bot.Bot, m *tb.Message, params []string) {
+	chatIdentifier := b.ChatIdentifier(m.Chat)
+	if len(params) < 1 {
+		b.Bot.Reply(m, "Invalid renew params. Format:\n/renew <your_ticket>", tb.Silent, tb.NoPreview)
+		return
+	}
+	ticObj, err := service.GetTicketObj(nil, params[0])
+	if err != nil {
+		b.Bot.Reply(m, err.Error(), tb.Silent, tb.NoPreview)
+		return
+	}
+	if ticObj.ChatIdentifier != chatIdentifier || ticObj.Type != model.TicketTypeUser {
+		b.Bot.Reply(m, service.ErrInvalidTicket.Error(), tb.Silent, tb.NoPreview)
+		return
+	}
+	log.Info("Renew: chatIdentifier: %v, ticket: %v", chatIdentifier, ticObj.Masked())
+	renewedTic, err := service.SaveTicket(nil, ticObj.Ticket, ticObj.Type, chatIdentifier, b.Member(m))
+	if err != nil {
+		b.Bot.Reply(m, err.Error(), tb.Silent, tb.NoPreview)
+		return
+	}
+	if common.Expired(ticObj.ExpireAt) {
+		if err = service.ReqSyncPassagesByChatIdentifier(nil, chatIdentifier, true); err != nil {
+			log.Warn("ReqSyncPassagesByChatIdentifier: %v", err)
+		}
+	}
+	b.Bot.Reply(m, ticketReply(renewedTic), tb.Silent, tb.NoPreview)
+}
@@ -0,0 +1,89 @@
+package bot
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/pkg/log"
+	jsoniter "github.com/json-iterator/go"
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+const (
+	// WebhookPath is the path of the endpoint to receive updates from telegram.
+	WebhookPath = "/telegram/webhook"
+	// secretTokenHeader is the header carrying the secret token given by setWebhook.
+	secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+)
+
+// secretTokenRegexp is the format of the secret token accepted by setWebhook.
+var secretTokenRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+
+// Webhook is a tb.Poller receiving updates by the webhook. Different from tb.Webhook, it does not listen
+// by itself but serves as a http.Handler mounted on the existing web server, and verifies the secret token.
+type Webhook struct {
+	// PublicURL is the URL of the webhook endpoint for telegram to post updates to.
+	PublicURL string
+	// SecretToken is sent by telegram in the header of every request to prove the requests come from it.
+	SecretToken string
+
+	updates chan tb.Update
+}
+
+func NewWebhook(publicURL string, secretToken string) (*Webhook, error) {
+	if !secretTokenRegexp.MatchString(secretToken) {
+		return nil, fmt.Errorf("invalid secret token: only 1-256 characters of A-Z, a-z, 0-9, _ and - are allowed")
+	}
+	return &Webhook{
+		PublicURL:   publicURL,
+		SecretToken: secretToken,
+		updates:     make(chan tb.Update, 64),
+	}, nil
+}
+
+// set sets the webhook of the bot to the PublicURL with the SecretToken.
+func (h *Webhook) set(b *tb.Bot) error {
+	if _, err := b.Raw("setWebhook", map[string]string{
+		"url":          h.PublicURL,
+		"secret_token": h.SecretToken,
+	}); err != nil {
+		return err
+	}
+	log.Info("Bot: webhook is set to %v", h.PublicURL)
+	return nil
+}
+
+// Poll forwards the received updates to the bot until it is stopped. The webhook is set by New.
+func (h *Webhook) Poll(b *tb.Bot, dest chan tb.Update, stop chan struct{}) {
+	for {
+		select {
+		case upd := <-h.updates:
+			dest <- upd
+		case <-stop:
+			return
+		}
+	}
+}
+
+// ServeHTTP receives an update from telegram.
+func (h *Webhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get(secretTokenHeader)), []byte(h.SecretToken)) != 1 {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var update tb.Update
+	if err := jsoniter.NewDecoder(r.Body).Decode(&update); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	select {
+	case h.updates <- update:
+	case <-r.Context().Done():
+		// telegram will retry it later
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,145 @@
+package bot
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	tb "gopkg.in/tucnak/telebot.v2"
+)
+
+const testToken = "123456:test"
+
+// fakeBotAPI is a Bot API server recording the parameters of setWebhook.
+type fakeBotAPI struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	setWebhook map[string]string
+	// failSetWebhook makes setWebhook fail
+	failSetWebhook bool
+}
+
+func newFakeBotAPI(t *testing.T) *fakeBotAPI {
+	api := &fakeBotAPI{}
+	api.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		api.mu.Lock()
+		defer api.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch strings.TrimPrefix(r.URL.Path, "/bot"+testToken+"/") {
+		case "getMe":
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"id":1,"is_bot":true,"first_name":"test","username":"test_bot"}}`))
+		case "setWebhook":
+			if err := jsoniter.NewDecoder(r.Body).Decode(&api.setWebhook); err != nil {
+				t.Errorf("setWebhook: %v", err)
+			}
+			if api.failSetWebhook {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"ok":false,"error_code":400,"description":"Bad Request: bad webhook"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"ok":true,"result":true}`))
+		default:
+			t.Errorf("unexpected request: %v", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"ok":false,"error_code":404,"description":"Not Found"}`))
+		}
+	}))
+	t.Cleanup(api.Close)
+	return api
+}
+
+func TestWebhook(t *testing.T) {
+	api := newFakeBotAPI(t)
+	webhook, err := NewWebhook("https://example.com"+WebhookPath, "secret_Token-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := New(testToken, api.URL, webhook)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"url": "https://example.com" + WebhookPath, "secret_token": "secret_Token-1"}
+	if !reflect.DeepEqual(api.setWebhook, want) {
+		t.Fatalf("setWebhook: got %v, want %v", api.setWebhook, want)
+	}
+
+	received := make(chan []string, 1)
+	RegisterCommands("webhooktest", func(b *Bot, m *tb.Message, params []string) {
+		received <- params
+	})
+	defer delete(GlobalCommandMapper, "webhooktest")
+	go b.Start()
+	defer b.Bot.Stop()
+
+	server := httptest.NewServer(webhook)
+	defer server.Close()
+	post := func(secretToken string) int {
+		req, err := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(
+			`{"update_id":1,"channel_post":{"message_id":1,"date":1,"chat":{"id":-1001,"type":"channel"},"text":"/webhooktest a b"}}`,
+		))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if secretToken != "" {
+			req.Header.Set(secretTokenHeader, secretToken)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	for _, secretToken := range []string{"", "secret_Token-2"} {
+		if code := post(secretToken); code != http.StatusUnauthorized {
+			t.Fatalf("secret token %q: got status %v, want %v", secretToken, code, http.StatusUnauthorized)
+		}
+	}
+	select {
+	case params := <-received:
+		t.Fatalf("the handler received an unauthorized update: %v", params)
+	default:
+	}
+	if code := post("secret_Token-1"); code != http.StatusOK {
+		t.Fatalf("got status %v, want %v", code, http.StatusOK)
+	}
+	select {
+	case params := <-received:
+		if !reflect.DeepEqual(params, []string{"a", "b"}) {
+			t.Fatalf("unexpected params: %v", params)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the handler did not receive the update")
+	}
+}
+
+func TestWebhookSetFailure(t *testing.T) {
+	api := newFakeBotAPI(t)
+	api.failSetWebhook = true
+	webhook, err := NewWebhook("https://example.com"+WebhookPath, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = New(testToken, api.URL, webhook); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestNewWebhookSecretToken(t *testing.T) {
+	for _, secretToken := range []string{"", "with space", "slash/", strings.Repeat("a", 257)} {
+		if _, err := NewWebhook("https://example.com"+WebhookPath, secretToken); err == nil {
+			t.Errorf("secret token %q: expected an error", secretToken)
+		}
+	}
+	for _, secretToken := range []string{"a", "A-z_0", strings.Repeat("a", 256)} {
+		if _, err := NewWebhook("https://example.com"+WebhookPath, secretToken); err != nil {
+			t.Errorf("secret token %q: %v", secretToken, err)
+		}
+	}
+}
@@ -17,9 +17,16 @@ type Params struct {
 	Config              string `id:"config" short:"c" default:"$HOME/.config/sweetlisa" desc:"SweetLisa configuration directory"`
 	CNProxy             string `id:"cn-proxy" desc:"The https proxy for sweetlisa to connect to the servers and relays in China"`
 	BotToken            string `id:"bot-token"`
+	BotAPIURL           string `id:"bot-api-url" desc:"The Telegram Bot API server. Leave it empty to use https://api.telegram.org"`
+	BotWebhook          bool   `id:"bot-webhook" desc:"Receive updates of the bot by the webhook at https://<host>/telegram/webhook instead of long polling"`
+	BotWebhookSecret    string `id:"bot-webhook-secret" desc:"The secret token to verify the webhook requests. Only 1-256 characters of A-Z, a-z, 0-9, _ and - are allowed. A random one is used if it is empty"`
 	Host                string `id:"host" default:"example.org"`
 	NameserverName      string `id:"nameserver-name" desc:"nameserver name of given token"`
 	NameserverToken     string `id:"nameserver-token" desc:"nameserver token to set DNS for BitterJohn's TLS challenge"`
+	ACMEDirectory       string `id:"acme-directory" desc:"ACME directory URL to issue certificates for the subdomains of TLS servers by the nameserver, such as https://acme-v02.api.letsencrypt.org/directory. Servers obtain their own certificates if it is empty"`
+	ACMEEmail           string `id:"acme-email" desc:"Optional contact email of the ACME account"`
+	MaxRelayHops        int    `id:"max-relay-hops" default:"1" desc:"Maximum number of relays in a relay chain. Passages grow exponentially with it"`
+	MetricsToken        string `id:"metrics-token" desc:"The bearer token to access /metrics. /metrics is disabled if it is empty"`
 	LogLevel            string `id:"log-level" default:"info" desc:"Optional values: trace, debug, info, warn or error"`
 	LogFile             string `id:"log-file" desc:"The path of log file"`
 	LogMaxDays          int64  `id:"log-max-days" default:"3" desc:"Maximum number of days to keep log files"`
@@ -36,7 +43,8 @@ func initFunc() {
 		EnvPrefix:         "LISA_",
 	})
 	if err != nil {
-		if err.Error() != "unexpected word while parsing flags: '-test.v'" {
+		// flags of go test
+		if !strings.HasPrefix(err.Error(), "unexpected word while parsing flags: '-test.") {
 			log2.Fatal(err)
 		}
 	}
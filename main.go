@@ -2,17 +2,28 @@ package main
 
 import (
 	"embed"
+	"net/url"
 
 	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/bot"
 	_ "github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/bot/command_handler"
 	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/config"
+	_ "github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/manager/hysteria2"
 	_ "github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/manager/juicity"
 	_ "github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/manager/shadowsocks"
+	_ "github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/manager/trojan"
+	_ "github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/manager/vless"
 	_ "github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/manager/vmess"
 	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/pkg/log"
 	_ "github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/pkg/nameserver/cloudflare"
 	_ "github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/pkg/proxy_http"
+	_ "github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/renderer/base64"
+	_ "github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/renderer/clash"
+	_ "github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/renderer/singbox"
+	_ "github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/renderer/sip008"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/service"
 	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/webserver/router"
+	gonanoid "github.com/matoous/go-nanoid/v2"
+	tb "gopkg.in/tucnak/telebot.v2"
 )
 
 //go:embed static/*
@@ -21,11 +32,34 @@ var f embed.FS
 func main() {
 	GoBackgrounds()
 	go SyncAll()
+	conf := config.GetConfig()
+	var webhook *bot.Webhook
+	if conf.BotWebhook {
+		secret := conf.BotWebhookSecret
+		if secret == "" {
+			secret = gonanoid.Must(32)
+		}
+		u := url.URL{
+			Scheme: "https",
+			Host:   conf.Host,
+			Path:   bot.WebhookPath,
+		}
+		var err error
+		if webhook, err = bot.NewWebhook(u.String(), secret); err != nil {
+			log.Fatal("Bot: %v", err)
+		}
+	}
 	go func() {
-		_, err := bot.New(config.GetConfig().BotToken, nil)
+		var poller tb.Poller
+		if webhook != nil {
+			poller = webhook
+		}
+		b, err := bot.New(conf.BotToken, conf.BotAPIURL, poller)
 		if err != nil {
 			log.Fatal("Bot: %v", err)
 		}
+		service.SetNotifier(b.Notify)
+		b.Start()
 	}()
-	log.Fatal("%v", router.Run(f))
+	log.Fatal("%v", router.Run(f, webhook))
 }
@@ -0,0 +1,314 @@
+package hysteria2
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/daeuniverse/softwind/netproxy"
+	"github.com/daeuniverse/softwind/protocol"
+	"github.com/daeuniverse/softwind/protocol/direct"
+	tuicCommon "github.com/daeuniverse/softwind/protocol/tuic/common"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/common"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/manager"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/model"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/pkg/log"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/mzz2017/quic-go"
+	"github.com/mzz2017/quic-go/http3"
+	"github.com/mzz2017/quic-go/quicvarint"
+)
+
+const (
+	frameTypeTCPRequest = 0x401
+	statusAuthOK        = 233
+)
+
+func init() {
+	manager.Register(string(model.ProtocolHysteria2), New)
+}
+
+type Hysteria2 struct {
+	dialer     manager.Dialer
+	arg        manager.ManageArgument
+	pinnedHash []byte
+}
+
+func New(dialer manager.Dialer, arg manager.ManageArgument) (manager.Manager, error) {
+	pin := strings.ReplaceAll(strings.ToLower(common.SimplyGetParam(arg.Method, "pinSHA256")), ":", "")
+	pinnedHash, err := hex.DecodeString(pin)
+	if err != nil || len(pinnedHash) != sha256.Size {
+		return nil, fmt.Errorf("failed to decode pinSHA256")
+	}
+	return &Hysteria2{
+		dialer:     dialer,
+		arg:        arg,
+		pinnedHash: pinnedHash,
+	}, nil
+}
+
+// padding returns a random string to obscure the length of messages.
+func padding(min, max int) string {
+	n, _ := rand.Int(rand.Reader, big.NewInt(int64(max-min)))
+	return strings.Repeat("0", min+int(n.Int64()))
+}
+
+// closers closes all in reverse order.
+type closers []io.Closer
+
+func (c closers) Close() error {
+	for i := len(c) - 1; i >= 0; i-- {
+		_ = c[i].Close()
+	}
+	return nil
+}
+
+type quicConnCloser struct {
+	quic.Connection
+}
+
+func (c quicConnCloser) Close() error {
+	return c.CloseWithError(0, "")
+}
+
+func (s *Hysteria2) dialQuic(ctx context.Context) (conn quic.EarlyConnection, closer closers, err error) {
+	dialer := s.dialer
+	if dialer == nil {
+		dialer = &netproxy.ContextDialerConverter{
+			Dialer: direct.SymmetricDirect,
+		}
+	}
+	addr := net.JoinHostPort(s.arg.Host, s.arg.Port)
+	rAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := dialer.DialContext(ctx, "udp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	var pc net.PacketConn = &netproxy.FakeNetPacketConn{
+		PacketConn: c.(netproxy.PacketConn),
+		LAddr:      net.UDPAddrFromAddrPort(tuicCommon.GetUniqueFakeAddrPort()),
+		RAddr:      rAddr,
+	}
+	closer = append(closer, pc)
+	switch obfs := common.SimplyGetParam(s.arg.Method, "obfs"); obfs {
+	case "":
+	case "salamander":
+		pc = newSalamanderConn(pc, common.SimplyGetParam(s.arg.Method, "obfs-password"))
+	default:
+		closer.Close()
+		return nil, nil, fmt.Errorf("unsupported obfs: %v", obfs)
+	}
+	conn, err = quic.DialEarly(ctx, pc, rAddr, &tls.Config{
+		NextProtos:         []string{http3.NextProtoH3},
+		MinVersion:         tls.VersionTLS13,
+		ServerName:         common.SimplyGetParam(s.arg.Method, "sni"),
+		InsecureSkipVerify: true,
+		VerifyPeerCertificate: func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+			for _, cert := range rawCerts {
+				hash := sha256.Sum256(cert)
+				if bytes.Equal(hash[:], s.pinnedHash) {
+					return nil
+				}
+			}
+			return fmt.Errorf("no certificate matches the pinned hash")
+		},
+	}, &quic.Config{
+		HandshakeIdleTimeout: 10 * time.Second,
+		MaxIdleTimeout:       30 * time.Second,
+	})
+	if err != nil {
+		closer.Close()
+		return nil, nil, err
+	}
+	return conn, append(closer, quicConnCloser{conn}), nil
+}
+
+// auth authenticates the connection by the HTTP/3 request defined by Hysteria2.
+func (s *Hysteria2) auth(ctx context.Context, conn quic.EarlyConnection) (rt *http3.RoundTripper, err error) {
+	rt = &http3.RoundTripper{
+		Dial: func(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.EarlyConnection, error) {
+			return conn, nil
+		},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://hysteria/auth", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Hysteria-Auth", s.arg.Password)
+	// let the server decide the congestion control
+	req.Header.Set("Hysteria-CC-RX", "0")
+	req.Header.Set("Hysteria-Padding", padding(256, 2048))
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		rt.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != statusAuthOK {
+		rt.Close()
+		return nil, fmt.Errorf("failed to authenticate: status code %v", resp.StatusCode)
+	}
+	return rt, nil
+}
+
+func (s *Hysteria2) GetTurn(ctx context.Context, cmd protocol.MetadataCmd, body []byte) (respBody *manager.ReaderCloser, err error) {
+	if len(body) >= 1<<17 {
+		log.Trace("GetTurn(hysteria2): to: %v, len(body): %v", net.JoinHostPort(s.arg.Host, s.arg.Port), len(body))
+	}
+	conn, closer, err := s.dialQuic(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rt, err := s.auth(ctx, conn)
+	if err != nil {
+		closer.Close()
+		return nil, err
+	}
+	closer = append(closer, rt)
+	stream, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		closer.Close()
+		return nil, err
+	}
+	closer = append(closer, stream)
+	go func() {
+		<-ctx.Done()
+		stream.SetDeadline(time.Now())
+	}()
+
+	// the management message is carried by a TCP request to the magic address
+	addr := net.JoinHostPort(model.Hysteria2MsgHost, strconv.Itoa(int(cmd)))
+	pad := padding(64, 512)
+	req := quicvarint.Append(nil, frameTypeTCPRequest)
+	req = quicvarint.Append(req, uint64(len(addr)))
+	req = append(req, addr...)
+	req = quicvarint.Append(req, uint64(len(pad)))
+	req = append(req, pad...)
+	req = binary.BigEndian.AppendUint32(req, uint32(len(body)))
+	req = append(req, body...)
+	if _, err = stream.Write(req); err != nil {
+		closer.Close()
+		return nil, err
+	}
+
+	r := quicvarint.NewReader(stream)
+	status, err := r.ReadByte()
+	if err != nil {
+		closer.Close()
+		return nil, err
+	}
+	msgLen, err := quicvarint.Read(r)
+	if err != nil {
+		closer.Close()
+		return nil, err
+	}
+	msg := make([]byte, msgLen)
+	if _, err = io.ReadFull(r, msg); err != nil {
+		closer.Close()
+		return nil, err
+	}
+	if status != 0 {
+		closer.Close()
+		return nil, fmt.Errorf("request rejected: %v", string(msg))
+	}
+	padLen, err := quicvarint.Read(r)
+	if err != nil {
+		closer.Close()
+		return nil, err
+	}
+	if _, err = io.CopyN(io.Discard, r, int64(padLen)); err != nil {
+		closer.Close()
+		return nil, err
+	}
+	var lenBuf [4]byte
+	if _, err = io.ReadFull(r, lenBuf[:]); err != nil {
+		closer.Close()
+		return nil, err
+	}
+	return &manager.ReaderCloser{Reader: io.LimitReader(r, int64(binary.BigEndian.Uint32(lenBuf[:]))), Closer: closer}, nil
+}
+
+func (s *Hysteria2) Ping(ctx context.Context) (resp *model.PingResp, err error) {
+	respBody, err := s.GetTurn(ctx, protocol.MetadataCmdPing, []byte("ping"))
+	if err != nil {
+		return nil, err
+	}
+	defer respBody.Closer.Close()
+	var r model.PingResp
+	if err = jsoniter.NewDecoder(respBody.Reader).Decode(&r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (s *Hysteria2) SyncPassages(ctx context.Context, passages []model.Passage) (err error) {
+	body, err := jsoniter.Marshal(passages)
+	if err != nil {
+		return err
+	}
+	respBody, err := s.GetTurn(ctx, protocol.MetadataCmdSyncPassages, body)
+	if err != nil {
+		return err
+	}
+	defer respBody.Closer.Close()
+	var buf = make([]byte, 2)
+	if _, err = io.ReadFull(respBody.Reader, buf); err != nil {
+		return err
+	}
+	if !bytes.Equal(buf, []byte("OK")) {
+		return fmt.Errorf("unexpected SyncPassages response from server: %v", string(buf))
+	}
+	return nil
+}
+
+func (s *Hysteria2) DeliverCertificate(ctx context.Context, cert model.Certificate) (err error) {
+	body, err := jsoniter.Marshal(cert)
+	if err != nil {
+		return err
+	}
+	respBody, err := s.GetTurn(ctx, model.MetadataCmdDeliverCertificate, body)
+	if err != nil {
+		return err
+	}
+	defer respBody.Closer.Close()
+	var buf = make([]byte, 2)
+	if _, err = io.ReadFull(respBody.Reader, buf); err != nil {
+		return err
+	}
+	if !bytes.Equal(buf, []byte("OK")) {
+		return fmt.Errorf("unexpected DeliverCertificate response from server: %v", string(buf))
+	}
+	return nil
+}
+
+func (s *Hysteria2) ProbeUpstreams(ctx context.Context, targets []model.ProbeTarget) (results []model.ProbeResult, err error) {
+	body, err := jsoniter.Marshal(targets)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := s.GetTurn(ctx, model.MetadataCmdProbeUpstreams, body)
+	if err != nil {
+		return nil, err
+	}
+	defer respBody.Closer.Close()
+	if err = jsoniter.NewDecoder(respBody.Reader).Decode(&results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
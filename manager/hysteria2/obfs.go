@@ -0,0 +1,61 @@
+package hysteria2
+
+import (
+	"crypto/rand"
+	"net"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+const salamanderSaltLen = 8
+
+// salamanderConn obfuscates the packets by XORing them with the BLAKE2b-256 of the password and a random salt,
+// which is prepended to the packet.
+type salamanderConn struct {
+	net.PacketConn
+	password []byte
+}
+
+func newSalamanderConn(conn net.PacketConn, password string) *salamanderConn {
+	return &salamanderConn{
+		PacketConn: conn,
+		password:   []byte(password),
+	}
+}
+
+func (c *salamanderConn) key(salt []byte) [blake2b.Size256]byte {
+	return blake2b.Sum256(append(append([]byte{}, c.password...), salt...))
+}
+
+func (c *salamanderConn) ReadFrom(p []byte) (n int, addr net.Addr, err error) {
+	for {
+		n, addr, err = c.PacketConn.ReadFrom(p)
+		if err != nil {
+			return 0, addr, err
+		}
+		if n <= salamanderSaltLen {
+			// drop the invalid packet
+			continue
+		}
+		key := c.key(p[:salamanderSaltLen])
+		for i := salamanderSaltLen; i < n; i++ {
+			p[i-salamanderSaltLen] = p[i] ^ key[(i-salamanderSaltLen)%len(key)]
+		}
+		return n - salamanderSaltLen, addr, nil
+	}
+}
+
+func (c *salamanderConn) WriteTo(p []byte, addr net.Addr) (n int, err error) {
+	buf := make([]byte, salamanderSaltLen+len(p))
+	if _, err = rand.Read(buf[:salamanderSaltLen]); err != nil {
+		return 0, err
+	}
+	key := c.key(buf[:salamanderSaltLen])
+	for i := range p {
+		buf[salamanderSaltLen+i] = p[i] ^ key[i%len(key)]
+	}
+	if _, err = c.PacketConn.WriteTo(buf, addr); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -140,3 +140,39 @@ func (s *Juicity) SyncPassages(ctx context.Context, passages []model.Passage) (e
 	}
 	return nil
 }
+
+func (s *Juicity) DeliverCertificate(ctx context.Context, cert model.Certificate) (err error) {
+	body, err := jsoniter.Marshal(cert)
+	if err != nil {
+		return err
+	}
+	respBody, err := s.GetTurn(ctx, model.MetadataCmdDeliverCertificate, body)
+	if err != nil {
+		return err
+	}
+	defer respBody.Closer.Close()
+	var buf = make([]byte, 2)
+	if _, err = io.ReadFull(respBody.Reader, buf); err != nil {
+		return err
+	}
+	if !bytes.Equal(buf, []byte("OK")) {
+		return fmt.Errorf("unexpected DeliverCertificate response from server: %v", string(buf))
+	}
+	return nil
+}
+
+func (s *Juicity) ProbeUpstreams(ctx context.Context, targets []model.ProbeTarget) (results []model.ProbeResult, err error) {
+	body, err := jsoniter.Marshal(targets)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := s.GetTurn(ctx, model.MetadataCmdProbeUpstreams, body)
+	if err != nil {
+		return nil, err
+	}
+	defer respBody.Closer.Close()
+	if err = jsoniter.NewDecoder(respBody.Reader).Decode(&results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -35,6 +35,10 @@ type ManageArgument struct {
 type Manager interface {
 	Ping(ctx context.Context) (resp *model.PingResp, err error)
 	SyncPassages(ctx context.Context, passages []model.Passage) (err error)
+	// ProbeUpstreams asks the relay to measure the RTTs to given upstream hosts
+	ProbeUpstreams(ctx context.Context, targets []model.ProbeTarget) (results []model.ProbeResult, err error)
+	// DeliverCertificate sends the certificate of the subdomain issued by SweetLisa to the server
+	DeliverCertificate(ctx context.Context, cert model.Certificate) (err error)
 }
 
 type ReaderCloser struct {
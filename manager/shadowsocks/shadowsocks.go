@@ -32,7 +32,11 @@ type Shadowsocks struct {
 }
 
 func New(dialer manager.Dialer, arg manager.ManageArgument) (manager.Manager, error) {
-	cipherConf := ciphers.AeadCiphersConf[arg.Argument.Method]
+	cipherConf, ok := ciphers.AeadCiphersConf[arg.Argument.Method]
+	if !ok {
+		// AEAD-2022 ciphers are only for users
+		return nil, fmt.Errorf("unsupported cipher to manage: %v", arg.Argument.Method)
+	}
 	masterKey := common.EVPBytesToKey(arg.Argument.Password, cipherConf.KeyLen)
 	return &Shadowsocks{
 		dialer:     dialer,
@@ -116,3 +120,39 @@ func (s *Shadowsocks) SyncPassages(ctx context.Context, passages []model.Passage
 	}
 	return nil
 }
+
+func (s *Shadowsocks) DeliverCertificate(ctx context.Context, cert model.Certificate) (err error) {
+	body, err := jsoniter.Marshal(cert)
+	if err != nil {
+		return err
+	}
+	respBody, err := s.GetTurn(ctx, model.MetadataCmdDeliverCertificate, body)
+	if err != nil {
+		return err
+	}
+	defer respBody.Closer.Close()
+	var buf = make([]byte, 2)
+	if _, err = io.ReadFull(respBody.Reader, buf); err != nil {
+		return err
+	}
+	if !bytes.Equal(buf, []byte("OK")) {
+		return fmt.Errorf("unexpected DeliverCertificate response from server: %v", string(buf))
+	}
+	return nil
+}
+
+func (s *Shadowsocks) ProbeUpstreams(ctx context.Context, targets []model.ProbeTarget) (results []model.ProbeResult, err error) {
+	body, err := jsoniter.Marshal(targets)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := s.GetTurn(ctx, model.MetadataCmdProbeUpstreams, body)
+	if err != nil {
+		return nil, err
+	}
+	defer respBody.Closer.Close()
+	if err = jsoniter.NewDecoder(respBody.Reader).Decode(&results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
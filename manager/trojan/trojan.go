@@ -0,0 +1,164 @@
+package trojan
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/daeuniverse/softwind/netproxy"
+	"github.com/daeuniverse/softwind/protocol"
+	"github.com/daeuniverse/softwind/protocol/direct"
+	"github.com/daeuniverse/softwind/protocol/trojanc"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/common"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/manager"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/model"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/pkg/log"
+	jsoniter "github.com/json-iterator/go"
+)
+
+func init() {
+	manager.Register(string(model.ProtocolTrojan), New)
+}
+
+type Trojan struct {
+	dialer manager.Dialer
+	arg    manager.ManageArgument
+}
+
+func New(dialer manager.Dialer, arg manager.ManageArgument) (manager.Manager, error) {
+	return &Trojan{
+		dialer: dialer,
+		arg:    arg,
+	}, nil
+}
+
+func (s *Trojan) GetTurn(ctx context.Context, cmd protocol.MetadataCmd, body []byte) (respBody *manager.ReaderCloser, err error) {
+	if len(body) >= 1<<17 {
+		log.Trace("GetTurn(trojan): to: %v, len(body): %v", net.JoinHostPort(s.arg.Host, s.arg.Port), len(body))
+	}
+	dialer := s.dialer
+	if dialer == nil {
+		dialer = &netproxy.ContextDialerConverter{
+			Dialer: direct.SymmetricDirect,
+		}
+	}
+	sni, err := common.HostToSNI(s.arg.Host, s.arg.RootDomain)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(s.arg.Host, s.arg.Port))
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		<-ctx.Done()
+		conn.SetDeadline(time.Now())
+	}()
+	tlsConn := tls.Client(&netproxy.FakeNetConn{Conn: conn}, &tls.Config{
+		ServerName: sni,
+		NextProtos: []string{"h2", "http/1.1"},
+	})
+	if err = tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	tConn, err := trojanc.NewConn(tlsConn, trojanc.Metadata{
+		Metadata: protocol.Metadata{
+			Type:     protocol.MetadataTypeMsg,
+			Cmd:      cmd,
+			IsClient: true,
+		},
+		Network: "tcp",
+	}, s.arg.Password)
+	if err != nil {
+		tlsConn.Close()
+		return nil, err
+	}
+	req := make([]byte, len(body)+4)
+	binary.BigEndian.PutUint32(req, uint32(len(body)))
+	copy(req[4:], body)
+	if _, err = tConn.Write(req); err != nil {
+		tConn.Close()
+		return nil, err
+	}
+	// reuse the req variable to read length
+	if _, err = io.ReadFull(tConn, req[:4]); err != nil {
+		tConn.Close()
+		return nil, err
+	}
+	return &manager.ReaderCloser{Reader: io.LimitReader(tConn, int64(binary.BigEndian.Uint32(req[:4]))), Closer: tConn}, nil
+}
+
+func (s *Trojan) Ping(ctx context.Context) (resp *model.PingResp, err error) {
+	respBody, err := s.GetTurn(ctx, protocol.MetadataCmdPing, []byte("ping"))
+	if err != nil {
+		return nil, err
+	}
+	defer respBody.Closer.Close()
+	var r model.PingResp
+	if err = jsoniter.NewDecoder(respBody.Reader).Decode(&r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (s *Trojan) SyncPassages(ctx context.Context, passages []model.Passage) (err error) {
+	body, err := jsoniter.Marshal(passages)
+	if err != nil {
+		return err
+	}
+	respBody, err := s.GetTurn(ctx, protocol.MetadataCmdSyncPassages, body)
+	if err != nil {
+		return err
+	}
+	defer respBody.Closer.Close()
+	var buf = make([]byte, 2)
+	if _, err = io.ReadFull(respBody.Reader, buf); err != nil {
+		return err
+	}
+	if !bytes.Equal(buf, []byte("OK")) {
+		return fmt.Errorf("unexpected SyncPassages response from server: %v", string(buf))
+	}
+	return nil
+}
+
+func (s *Trojan) DeliverCertificate(ctx context.Context, cert model.Certificate) (err error) {
+	body, err := jsoniter.Marshal(cert)
+	if err != nil {
+		return err
+	}
+	respBody, err := s.GetTurn(ctx, model.MetadataCmdDeliverCertificate, body)
+	if err != nil {
+		return err
+	}
+	defer respBody.Closer.Close()
+	var buf = make([]byte, 2)
+	if _, err = io.ReadFull(respBody.Reader, buf); err != nil {
+		return err
+	}
+	if !bytes.Equal(buf, []byte("OK")) {
+		return fmt.Errorf("unexpected DeliverCertificate response from server: %v", string(buf))
+	}
+	return nil
+}
+
+func (s *Trojan) ProbeUpstreams(ctx context.Context, targets []model.ProbeTarget) (results []model.ProbeResult, err error) {
+	body, err := jsoniter.Marshal(targets)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := s.GetTurn(ctx, model.MetadataCmdProbeUpstreams, body)
+	if err != nil {
+		return nil, err
+	}
+	defer respBody.Closer.Close()
+	if err = jsoniter.NewDecoder(respBody.Reader).Decode(&results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,147 @@
+package vless
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+	"golang.org/x/crypto/hkdf"
+)
+
+// realityClientVersion is the Xray version to claim, which servers may limit by minClientVer and maxClientVer.
+var realityClientVersion = [3]byte{1, 8, 4}
+
+// RealityConfig is the client side configuration of REALITY.
+type RealityConfig struct {
+	ServerName  string
+	Fingerprint string
+	// PublicKey is the x25519 public key of the server in base64url without padding
+	PublicKey string
+	// ShortId is in hex
+	ShortId string
+}
+
+func fingerprintToClientHelloID(fingerprint string) utls.ClientHelloID {
+	switch strings.ToLower(fingerprint) {
+	case "firefox":
+		return utls.HelloFirefox_Auto
+	case "safari":
+		return utls.HelloSafari_Auto
+	case "ios":
+		return utls.HelloIOS_Auto
+	case "edge":
+		return utls.HelloEdge_Auto
+	case "random", "randomized":
+		return utls.HelloRandomized
+	default:
+		return utls.HelloChrome_Auto
+	}
+}
+
+// RealityClient performs the REALITY handshake on the conn. The server is authenticated by the temporary
+// certificate signed with the shared key, and the connection to a real website will be refused.
+func RealityClient(ctx context.Context, conn net.Conn, config RealityConfig) (*utls.UConn, error) {
+	return realityClient(ctx, conn, config, fingerprintToClientHelloID(config.Fingerprint), nil)
+}
+
+// realityClient performs the REALITY handshake with the hello of helloID, or the spec if it is not nil.
+func realityClient(ctx context.Context, conn net.Conn, config RealityConfig, helloID utls.ClientHelloID, spec *utls.ClientHelloSpec) (*utls.UConn, error) {
+	publicKey, err := base64.RawURLEncoding.DecodeString(config.PublicKey)
+	if err != nil || len(publicKey) != 32 {
+		return nil, fmt.Errorf("REALITY: invalid public key: %v", config.PublicKey)
+	}
+	shortId, err := hex.DecodeString(config.ShortId)
+	if err != nil || len(shortId) > 8 {
+		return nil, fmt.Errorf("REALITY: invalid short id: %v", config.ShortId)
+	}
+	var authKey []byte
+	var verified bool
+	uConn := utls.UClient(conn, &utls.Config{
+		ServerName:             config.ServerName,
+		InsecureSkipVerify:     true,
+		SessionTicketsDisabled: true,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 {
+				return fmt.Errorf("REALITY: no certificate")
+			}
+			cert, err := x509.ParseCertificate(rawCerts[0])
+			if err != nil {
+				return err
+			}
+			pub, ok := cert.PublicKey.(ed25519.PublicKey)
+			if !ok {
+				return fmt.Errorf("REALITY: not a REALITY server")
+			}
+			h := hmac.New(sha512.New, authKey)
+			h.Write(pub)
+			if !bytes.Equal(h.Sum(nil), cert.Signature) {
+				return fmt.Errorf("REALITY: not a REALITY server")
+			}
+			verified = true
+			return nil
+		},
+	}, helloID)
+	if spec != nil {
+		if err = uConn.ApplyPreset(spec); err != nil {
+			return nil, err
+		}
+	}
+	if err = uConn.BuildHandshakeState(); err != nil {
+		return nil, err
+	}
+	hello := uConn.HandshakeState.Hello
+	// the session id should be zero when calculating the AEAD, so zero it in the raw hello first
+	hello.SessionId = make([]byte, 32)
+	copy(hello.Raw[39:], hello.SessionId)
+	// the session id is the encrypted authentication:
+	// version(3) + reserved(1) + unix time(4) + short id(8), followed by the tag of AEAD
+	copy(hello.SessionId, realityClientVersion[:])
+	binary.BigEndian.PutUint32(hello.SessionId[4:], uint32(time.Now().Unix()))
+	copy(hello.SessionId[8:], shortId)
+
+	ecdhe, ok := uConn.HandshakeState.State13.KeySharesEcdheParams.GetPublicEcdheParams(utls.X25519)
+	if !ok {
+		if ecdhe = uConn.HandshakeState.State13.EcdheParams; ecdhe == nil || ecdhe.CurveID() != utls.X25519 {
+			return nil, fmt.Errorf("REALITY: the fingerprint %v does not support x25519", config.Fingerprint)
+		}
+	}
+	if authKey = ecdhe.SharedKey(publicKey); authKey == nil {
+		return nil, fmt.Errorf("REALITY: failed to calculate the shared key")
+	}
+	if _, err = hkdf.New(sha256.New, authKey, hello.Random[:20], []byte("REALITY")).Read(authKey); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(authKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	aead.Seal(hello.SessionId[:0], hello.Random[20:], hello.SessionId[:16], hello.Raw)
+	copy(hello.Raw[39:], hello.SessionId)
+
+	if err = uConn.HandshakeContext(ctx); err != nil {
+		return nil, err
+	}
+	if !verified {
+		uConn.Close()
+		return nil, fmt.Errorf("REALITY: not a REALITY server")
+	}
+	return uConn, nil
+}
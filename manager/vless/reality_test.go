@@ -0,0 +1,328 @@
+package vless
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+	"golang.org/x/crypto/hkdf"
+)
+
+// realityServer follows the authentication of the REALITY server (github.com/xtls/reality): the session id
+// is opened with raw[39:71] zeroed, and an authenticated client receives a certificate whose signature is
+// the HMAC of its ed25519 public key. Other clients receive a normal certificate as if they were forwarded
+// to the real website.
+//
+// The REALITY server is a fork of crypto/tls which signs by ed25519 even if the client does not offer it,
+// but crypto/tls used here does not, so the full handshake is done with the hello of Chrome offering ed25519.
+
+const (
+	extensionKeyShare = 51
+	groupX25519       = 29
+)
+
+type realityServer struct {
+	privateKey *ecdh.PrivateKey
+	shortId    [8]byte
+	// authenticated is sent the result of the authentication of every connection
+	authenticated chan bool
+}
+
+// readClientHello reads the first TLS record, which is the ClientHello.
+func readClientHello(conn net.Conn) (record []byte, err error) {
+	header := make([]byte, 5)
+	if _, err = io.ReadFull(conn, header); err != nil {
+		return nil, err
+	}
+	if header[0] != 22 {
+		return nil, fmt.Errorf("not a handshake record")
+	}
+	record = make([]byte, 5+int(binary.BigEndian.Uint16(header[3:])))
+	copy(record, header)
+	if _, err = io.ReadFull(conn, record[5:]); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// x25519KeyShare returns the x25519 key share of the raw ClientHello.
+func x25519KeyShare(raw []byte) ([]byte, error) {
+	// type(1) + length(3) + version(2) + random(32)
+	p := 38
+	p += 1 + int(raw[p])
+	p += 2 + int(binary.BigEndian.Uint16(raw[p:]))
+	p += 1 + int(raw[p])
+	end := p + 2 + int(binary.BigEndian.Uint16(raw[p:]))
+	p += 2
+	for p+4 <= end {
+		typ := binary.BigEndian.Uint16(raw[p:])
+		l := int(binary.BigEndian.Uint16(raw[p+2:]))
+		data := raw[p+4 : p+4+l]
+		p += 4 + l
+		if typ != extensionKeyShare {
+			continue
+		}
+		for q := 2; q+4 <= len(data); {
+			group := binary.BigEndian.Uint16(data[q:])
+			kl := int(binary.BigEndian.Uint16(data[q+2:]))
+			if group == groupX25519 {
+				return data[q+4 : q+4+kl], nil
+			}
+			q += 4 + kl
+		}
+	}
+	return nil, fmt.Errorf("no x25519 key share")
+}
+
+// authenticate returns the auth key if the client is authenticated.
+func (s *realityServer) authenticate(raw []byte) (authKey []byte, err error) {
+	share, err := x25519KeyShare(raw)
+	if err != nil {
+		return nil, err
+	}
+	peer, err := ecdh.X25519().NewPublicKey(share)
+	if err != nil {
+		return nil, err
+	}
+	if authKey, err = s.privateKey.ECDH(peer); err != nil {
+		return nil, err
+	}
+	random := raw[6:38]
+	if _, err = hkdf.New(sha256.New, authKey, random[:20], []byte("REALITY")).Read(authKey); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(authKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	sessionId := append([]byte(nil), raw[39:71]...)
+	zeroed := append([]byte(nil), raw...)
+	copy(zeroed[39:71], make([]byte, 32))
+	plainText, err := aead.Open(nil, random[20:], sessionId, zeroed)
+	if err != nil {
+		return nil, err
+	}
+	if d := time.Since(time.Unix(int64(binary.BigEndian.Uint32(plainText[4:])), 0)); d > time.Minute || d < -time.Minute {
+		return nil, fmt.Errorf("time difference: %v", d)
+	}
+	if !bytes.Equal(plainText[8:16], s.shortId[:]) {
+		return nil, fmt.Errorf("unexpected short id")
+	}
+	return authKey, nil
+}
+
+func (s *realityServer) serve(conn net.Conn) {
+	defer conn.Close()
+	record, err := readClientHello(conn)
+	if err != nil {
+		return
+	}
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, pub, priv)
+	if err != nil {
+		return
+	}
+	authKey, err := s.authenticate(record[5:])
+	s.authenticated <- err == nil
+	if err == nil {
+		// the signature of ed25519 is the last 64 bytes of the certificate
+		h := hmac.New(sha512.New, authKey)
+		h.Write(pub)
+		h.Sum(cert[:len(cert)-64])
+	}
+	tlsConn := tls.Server(&prefixConn{Conn: conn, Reader: io.MultiReader(bytes.NewReader(record), conn)}, &tls.Config{
+		MinVersion:   tls.VersionTLS13,
+		Certificates: []tls.Certificate{{Certificate: [][]byte{cert}, PrivateKey: priv}},
+	})
+	if err = tlsConn.Handshake(); err != nil {
+		return
+	}
+	// echo
+	_, _ = io.Copy(tlsConn, tlsConn)
+}
+
+type prefixConn struct {
+	net.Conn
+	io.Reader
+}
+
+func (c *prefixConn) Read(b []byte) (int, error) {
+	return c.Reader.Read(b)
+}
+
+func newRealityServer(t *testing.T) (s *realityServer, addr string) {
+	privateKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s = &realityServer{
+		privateKey:    privateKey,
+		shortId:       [8]byte{0x6b, 0xa8, 0x51, 0x79},
+		authenticated: make(chan bool, 1),
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s, ln.Addr().String()
+}
+
+// chromeWithEd25519 returns the hello spec of Chrome that offers the ed25519 signature algorithm.
+func chromeWithEd25519(t *testing.T) *utls.ClientHelloSpec {
+	spec, err := utls.UTLSIdToSpec(utls.HelloChrome_Auto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ext := range spec.Extensions {
+		if sigAlgs, ok := ext.(*utls.SignatureAlgorithmsExtension); ok {
+			sigAlgs.SupportedSignatureAlgorithms = append(sigAlgs.SupportedSignatureAlgorithms, utls.Ed25519)
+		}
+	}
+	return &spec
+}
+
+func dialReality(t *testing.T, addr string, config RealityConfig, helloID utls.ClientHelloID, spec *utls.ClientHelloSpec) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rConn, err := realityClient(ctx, conn, config, helloID, spec)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return rConn, nil
+}
+
+func TestRealityClient(t *testing.T) {
+	s, addr := newRealityServer(t)
+	conn, err := dialReality(t, addr, RealityConfig{
+		ServerName: "www.example.com",
+		PublicKey:  base64.RawURLEncoding.EncodeToString(s.privateKey.PublicKey().Bytes()),
+		ShortId:    hex.EncodeToString(s.shortId[:4]),
+	}, utls.HelloCustom, chromeWithEd25519(t))
+	if authenticated := <-s.authenticated; !authenticated {
+		t.Fatal("the server failed to authenticate the client")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err = conn.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 4)
+	if _, err = io.ReadFull(conn, buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "ping" {
+		t.Fatalf("unexpected echo: %q", buf)
+	}
+}
+
+func TestRealityClientFingerprints(t *testing.T) {
+	s, addr := newRealityServer(t)
+	for _, fingerprint := range []string{"chrome", "firefox", "safari", "ios", "edge"} {
+		t.Run(fingerprint, func(t *testing.T) {
+			conn, _ := dialReality(t, addr, RealityConfig{
+				ServerName:  "www.example.com",
+				Fingerprint: fingerprint,
+				PublicKey:   base64.RawURLEncoding.EncodeToString(s.privateKey.PublicKey().Bytes()),
+				ShortId:     hex.EncodeToString(s.shortId[:4]),
+			}, fingerprintToClientHelloID(fingerprint), nil)
+			if conn != nil {
+				conn.Close()
+			}
+			if authenticated := <-s.authenticated; !authenticated {
+				t.Fatal("the server failed to authenticate the client")
+			}
+		})
+	}
+}
+
+func TestRealityClientUnauthenticated(t *testing.T) {
+	s, addr := newRealityServer(t)
+	otherKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, config := range map[string]RealityConfig{
+		"public key": {
+			ServerName: "www.example.com",
+			PublicKey:  base64.RawURLEncoding.EncodeToString(otherKey.PublicKey().Bytes()),
+			ShortId:    hex.EncodeToString(s.shortId[:4]),
+		},
+		"short id": {
+			ServerName: "www.example.com",
+			PublicKey:  base64.RawURLEncoding.EncodeToString(s.privateKey.PublicKey().Bytes()),
+			ShortId:    "0123",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			conn, err := dialReality(t, addr, config, utls.HelloCustom, chromeWithEd25519(t))
+			if authenticated := <-s.authenticated; authenticated {
+				t.Fatal("the server should not authenticate the client")
+			}
+			if err == nil {
+				conn.Close()
+				t.Fatal("the client should not accept the certificate of the real website")
+			}
+		})
+	}
+}
+
+func TestRealityClientInvalidConfig(t *testing.T) {
+	for name, config := range map[string]RealityConfig{
+		"public key": {PublicKey: "invalid", ShortId: "01"},
+		"short id":   {PublicKey: base64.RawURLEncoding.EncodeToString(make([]byte, 32)), ShortId: "0123456789abcdef01"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c1, c2 := net.Pipe()
+			defer c1.Close()
+			defer c2.Close()
+			if _, err := RealityClient(context.Background(), c1, config); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package vless
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/daeuniverse/softwind/netproxy"
+	"github.com/daeuniverse/softwind/protocol"
+	"github.com/daeuniverse/softwind/protocol/direct"
+	vlessc "github.com/daeuniverse/softwind/protocol/vless"
+	"github.com/daeuniverse/softwind/protocol/vmess"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/common"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/manager"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/model"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/pkg/log"
+	jsoniter "github.com/json-iterator/go"
+)
+
+func init() {
+	manager.Register(string(model.ProtocolVLESSReality), New)
+}
+
+type VLESS struct {
+	dialer manager.Dialer
+	arg    manager.ManageArgument
+	key    []byte
+}
+
+func New(dialer manager.Dialer, arg manager.ManageArgument) (manager.Manager, error) {
+	key, err := vlessc.Password2Key(arg.Password)
+	if err != nil {
+		return nil, err
+	}
+	return &VLESS{
+		dialer: dialer,
+		arg:    arg,
+		key:    key,
+	}, nil
+}
+
+func (s *VLESS) GetTurn(ctx context.Context, cmd protocol.MetadataCmd, body []byte) (respBody *manager.ReaderCloser, err error) {
+	if len(body) >= 1<<17 {
+		log.Trace("GetTurn(vless): to: %v, len(body): %v", net.JoinHostPort(s.arg.Host, s.arg.Port), len(body))
+	}
+	dialer := s.dialer
+	if dialer == nil {
+		dialer = &netproxy.ContextDialerConverter{
+			Dialer: direct.SymmetricDirect,
+		}
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(s.arg.Host, s.arg.Port))
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		<-ctx.Done()
+		conn.SetDeadline(time.Now())
+	}()
+	rConn, err := RealityClient(ctx, &netproxy.FakeNetConn{Conn: conn}, RealityConfig{
+		ServerName:  common.SimplyGetParam(s.arg.Method, "sni"),
+		Fingerprint: common.SimplyGetParam(s.arg.Method, "fp"),
+		PublicKey:   s.arg.PublicKey,
+		ShortId:     s.arg.FirstShortId(),
+	})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	vConn, err := vlessc.NewConn(rConn, vmess.Metadata{
+		Metadata: protocol.Metadata{
+			Type:     protocol.MetadataTypeMsg,
+			Cmd:      cmd,
+			IsClient: true,
+		},
+		Network: "tcp",
+	}, s.key)
+	if err != nil {
+		rConn.Close()
+		return nil, err
+	}
+	req := make([]byte, len(body)+4)
+	binary.BigEndian.PutUint32(req, uint32(len(body)))
+	copy(req[4:], body)
+	if _, err = vConn.Write(req); err != nil {
+		vConn.Close()
+		return nil, err
+	}
+	// reuse the req variable to read length
+	if _, err = io.ReadFull(vConn, req[:4]); err != nil {
+		vConn.Close()
+		return nil, err
+	}
+	return &manager.ReaderCloser{Reader: io.LimitReader(vConn, int64(binary.BigEndian.Uint32(req[:4]))), Closer: vConn}, nil
+}
+
+func (s *VLESS) Ping(ctx context.Context) (resp *model.PingResp, err error) {
+	respBody, err := s.GetTurn(ctx, protocol.MetadataCmdPing, []byte("ping"))
+	if err != nil {
+		return nil, err
+	}
+	defer respBody.Closer.Close()
+	var r model.PingResp
+	if err = jsoniter.NewDecoder(respBody.Reader).Decode(&r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (s *VLESS) SyncPassages(ctx context.Context, passages []model.Passage) (err error) {
+	body, err := jsoniter.Marshal(passages)
+	if err != nil {
+		return err
+	}
+	respBody, err := s.GetTurn(ctx, protocol.MetadataCmdSyncPassages, body)
+	if err != nil {
+		return err
+	}
+	defer respBody.Closer.Close()
+	var buf = make([]byte, 2)
+	if _, err = io.ReadFull(respBody.Reader, buf); err != nil {
+		return err
+	}
+	if !bytes.Equal(buf, []byte("OK")) {
+		return fmt.Errorf("unexpected SyncPassages response from server: %v", string(buf))
+	}
+	return nil
+}
+
+func (s *VLESS) DeliverCertificate(ctx context.Context, cert model.Certificate) (err error) {
+	body, err := jsoniter.Marshal(cert)
+	if err != nil {
+		return err
+	}
+	respBody, err := s.GetTurn(ctx, model.MetadataCmdDeliverCertificate, body)
+	if err != nil {
+		return err
+	}
+	defer respBody.Closer.Close()
+	var buf = make([]byte, 2)
+	if _, err = io.ReadFull(respBody.Reader, buf); err != nil {
+		return err
+	}
+	if !bytes.Equal(buf, []byte("OK")) {
+		return fmt.Errorf("unexpected DeliverCertificate response from server: %v", string(buf))
+	}
+	return nil
+}
+
+func (s *VLESS) ProbeUpstreams(ctx context.Context, targets []model.ProbeTarget) (results []model.ProbeResult, err error) {
+	body, err := jsoniter.Marshal(targets)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := s.GetTurn(ctx, model.MetadataCmdProbeUpstreams, body)
+	if err != nil {
+		return nil, err
+	}
+	defer respBody.Closer.Close()
+	if err = jsoniter.NewDecoder(respBody.Reader).Decode(&results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
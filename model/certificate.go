@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+
+	"github.com/daeuniverse/softwind/protocol"
+)
+
+const (
+	BucketCertificate = "certificate"
+	// BucketACME stores the ACME account key
+	BucketACME = "acme"
+	// MetadataCmdDeliverCertificate delivers the certificate issued by SweetLisa to the server.
+	MetadataCmdDeliverCertificate = protocol.MetadataCmdResponse + 2
+	// CertificateRenewBefore is the time before the expiration to renew the certificate.
+	CertificateRenewBefore = 30 * 24 * time.Hour
+)
+
+// Certificate is the TLS certificate of the subdomain of a server.
+type Certificate struct {
+	Domain string
+	// CertificatePEM is the certificate chain in PEM
+	CertificatePEM string
+	// PrivateKeyPEM is the private key of the certificate in PEM
+	PrivateKeyPEM string
+	NotAfter      time.Time
+}
+
+// NeedRenew reports if the certificate is going to expire.
+func (c *Certificate) NeedRenew(now time.Time) bool {
+	return now.Add(CertificateRenewBefore).After(c.NotAfter)
+}
@@ -0,0 +1,24 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCertificateNeedRenew(t *testing.T) {
+	now := time.Now()
+	for _, c := range []struct {
+		notAfter time.Time
+		want     bool
+	}{
+		{now.Add(90 * 24 * time.Hour), false},
+		{now.Add(CertificateRenewBefore + time.Hour), false},
+		{now.Add(CertificateRenewBefore - time.Hour), true},
+		{now.Add(-time.Hour), true},
+	} {
+		cert := Certificate{NotAfter: c.notAfter}
+		if got := cert.NeedRenew(now); got != c.want {
+			t.Errorf("NeedRenew with NotAfter %v: got %v, want %v", c.notAfter.Sub(now), got, c.want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package model
+
+import "time"
+
+const (
+	BucketChat       = "chat"
+	MaxUsageResetDay = 28
+)
+
+// Chat is the settings of a chat.
+type Chat struct {
+	ChatIdentifier string
+	// UsageResetDay is the day of every month to reset the usages of user tickets. Zero means the first day.
+	UsageResetDay int `json:",omitempty"`
+	// RelayHostPolicy is the policy for relays to choose a host of the endpoint servers. Empty means failover.
+	RelayHostPolicy OutHostPolicy `json:",omitempty"`
+	// MaxRelayRTTMillis is the maximum RTT from relays to servers to show relay nodes in subscriptions.
+	// Zero means no limit.
+	MaxRelayRTTMillis int64 `json:",omitempty"`
+
+	// TelegramChatID is the ID of the channel to post notifications. It is recorded when notifications are enabled.
+	TelegramChatID int64 `json:",omitempty"`
+	// Notify indicates if the events of servers and relays are posted to the channel.
+	Notify bool `json:",omitempty"`
+	// MutedEvents are the events not to post even if Notify is true.
+	MutedEvents []NotificationEvent `json:",omitempty"`
+}
+
+func (c *Chat) GetUsageResetDay() int {
+	if c.UsageResetDay < 1 || c.UsageResetDay > MaxUsageResetDay {
+		return 1
+	}
+	return c.UsageResetDay
+}
+
+func (c *Chat) GetRelayHostPolicy() OutHostPolicy {
+	if !c.RelayHostPolicy.IsValid() {
+		return OutHostPolicyFailover
+	}
+	return c.RelayHostPolicy
+}
+
+// GetMaxRelayRTT returns the maximum RTT from relays to servers. Zero means no limit.
+func (c *Chat) GetMaxRelayRTT() time.Duration {
+	if c.MaxRelayRTTMillis <= 0 {
+		return 0
+	}
+	return time.Duration(c.MaxRelayRTTMillis) * time.Millisecond
+}
+
+// Muted reports if the event should not be posted to the channel.
+func (c *Chat) Muted(event NotificationEvent) bool {
+	for _, e := range c.MutedEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/common"
+)
+
+const (
+	CipherChacha20IetfPoly1305 = "chacha20-ietf-poly1305"
+	Cipher2022Blake3Aes128Gcm  = "2022-blake3-aes-128-gcm"
+	Cipher2022Blake3Aes256Gcm  = "2022-blake3-aes-256-gcm"
+)
+
+// ShadowsocksUserCiphers is the shadowsocks ciphers for users in the order of preference.
+var ShadowsocksUserCiphers = []string{
+	Cipher2022Blake3Aes256Gcm,
+	Cipher2022Blake3Aes128Gcm,
+	CipherChacha20IetfPoly1305,
+}
+
+// Is2022Cipher reports if the cipher is an AEAD-2022 cipher, whose password is a base64 PSK.
+func Is2022Cipher(cipher string) bool {
+	return strings.HasPrefix(cipher, "2022-")
+}
+
+// cipher2022KeyLen returns the PSK length of the AEAD-2022 cipher.
+func cipher2022KeyLen(cipher string) int {
+	if cipher == Cipher2022Blake3Aes128Gcm {
+		return 16
+	}
+	return 32
+}
+
+// GetCiphers returns the ciphers the server accepts for users.
+func (a Argument) GetCiphers() []string {
+	var ciphers []string
+	for _, cipher := range strings.Split(a.Ciphers, ",") {
+		if cipher = strings.TrimSpace(cipher); cipher != "" {
+			ciphers = append(ciphers, cipher)
+		}
+	}
+	return ciphers
+}
+
+// NegotiateUserCipher returns the most preferred cipher that the server accepts for users.
+// Servers that do not announce their ciphers only accept chacha20-ietf-poly1305.
+func (a Argument) NegotiateUserCipher() string {
+	accepted := a.GetCiphers()
+	for _, cipher := range ShadowsocksUserCiphers {
+		for _, c := range accepted {
+			if strings.EqualFold(c, cipher) {
+				return cipher
+			}
+		}
+	}
+	return CipherChacha20IetfPoly1305
+}
+
+// ShadowsocksPassword derives the password of the cipher from the seeds, which are tickets.
+func ShadowsocksPassword(cipher string, seeds ...string) string {
+	if Is2022Cipher(cipher) {
+		h := sha256.New()
+		for _, seed := range seeds {
+			h.Write([]byte(seed))
+		}
+		return base64.StdEncoding.EncodeToString(h.Sum(nil)[:cipher2022KeyLen(cipher)])
+	}
+	h := sha1.New()
+	for _, seed := range seeds {
+		h.Write([]byte(seed))
+	}
+	return common.Base62Encoder.Encode(h.Sum(nil))[:21]
+}
@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+const (
+	// NotificationInterval is the minimum interval between two notifications posted to the same chat.
+	NotificationInterval = 1 * time.Minute
+	// NotificationDelay is the time to wait for more events before posting, which coalesces the events
+	// of flapping servers into one message.
+	NotificationDelay = 30 * time.Second
+)
+
+// NotificationEvent is the type of events of servers and relays which can be muted.
+type NotificationEvent string
+
+const (
+	NotificationEventLaunch     NotificationEvent = "launch"
+	NotificationEventDisconnect NotificationEvent = "disconnect"
+	NotificationEventReconnect  NotificationEvent = "reconnect"
+	NotificationEventExhausted  NotificationEvent = "exhausted"
+	NotificationEventReset      NotificationEvent = "reset"
+	NotificationEventChanged    NotificationEvent = "changed"
+)
+
+var NotificationEvents = []NotificationEvent{
+	NotificationEventLaunch,
+	NotificationEventDisconnect,
+	NotificationEventReconnect,
+	NotificationEventExhausted,
+	NotificationEventReset,
+	NotificationEventChanged,
+}
+
+func (e NotificationEvent) IsValid() bool {
+	for _, event := range NotificationEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
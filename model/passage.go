@@ -10,9 +10,31 @@ type In struct {
 	Argument
 }
 
+type OutHostPolicy string
+
+const (
+	// OutHostPolicyFailover connects to the first available host of Hosts in order
+	OutHostPolicyFailover   OutHostPolicy = "failover"
+	OutHostPolicyRoundRobin OutHostPolicy = "round-robin"
+)
+
+func (p OutHostPolicy) IsValid() bool {
+	switch p {
+	case OutHostPolicyFailover, OutHostPolicyRoundRobin:
+		return true
+	default:
+		return false
+	}
+}
+
 type Out struct {
-	To   string
+	To string
+	// Host is the first of Hosts, which is kept for relays not supporting Hosts
 	Host string
 	Port string
+	// Hosts are the candidate hosts of the upstream server
+	Hosts []string `json:",omitempty"`
+	// HostPolicy is the policy to choose a host from Hosts
+	HostPolicy OutHostPolicy `json:",omitempty"`
 	Argument
 }
@@ -2,4 +2,16 @@ package model
 
 type PingResp struct {
 	BandwidthLimit BandwidthLimit
+	// PassageUsages is the traffic of passages since the last ping. It is empty if the server does not support it.
+	PassageUsages []PassageUsage `json:",omitempty"`
+}
+
+// PassageUsage is the traffic of a passage reported by the server.
+type PassageUsage struct {
+	// InHash is the Hash of the In.Argument of the passage
+	InHash string
+	// UplinkKiB is the traffic transmitted by the server in this passage.
+	UplinkKiB int64 `json:",omitempty"`
+	// DownlinkKiB is the traffic received by the server in this passage.
+	DownlinkKiB int64 `json:",omitempty"`
 }
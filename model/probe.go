@@ -0,0 +1,47 @@
+package model
+
+import (
+	"time"
+
+	"github.com/daeuniverse/softwind/protocol"
+)
+
+const (
+	BucketProbe = "probe"
+	// MetadataCmdProbeUpstreams asks the relay to measure the RTTs to its upstream servers.
+	MetadataCmdProbeUpstreams = protocol.MetadataCmdResponse + 1
+)
+
+// ProbeTarget is an upstream host for the relay to measure the RTT.
+type ProbeTarget struct {
+	To   string
+	Host string
+	Port string
+}
+
+// ProbeResult is the RTT measured by the relay.
+type ProbeResult struct {
+	To   string
+	Host string
+	// RTT is zero if the host is unreachable
+	RTT   time.Duration `json:",omitempty"`
+	Error string        `json:",omitempty"`
+}
+
+// RelayProbe is the latest RTTs measured by the relay to its upstream servers.
+type RelayProbe struct {
+	Ticket   string
+	ProbedAt time.Time
+	// RTTs is the mapping from upstream server tickets to the minimum RTTs of their hosts.
+	// Unreachable servers are absent.
+	RTTs map[string]time.Duration
+}
+
+// RTT returns the RTT from the relay to the server. ok is false if it was not measured or unreachable.
+func (p *RelayProbe) RTT(serverTicket string) (rtt time.Duration, ok bool) {
+	if p == nil {
+		return 0, false
+	}
+	rtt, ok = p.RTTs[serverTicket]
+	return rtt, ok
+}
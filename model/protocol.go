@@ -0,0 +1,30 @@
+package model
+
+import (
+	"github.com/daeuniverse/softwind/protocol"
+)
+
+// Protocols supported by SweetLisa but not defined by softwind.
+const (
+	ProtocolTrojan protocol.Protocol = "trojan+tls"
+	// ProtocolVLESSReality borrows the TLS handshake of the website given by "sni" of the Method,
+	// thus it needs neither a subdomain nor a certificate.
+	ProtocolVLESSReality protocol.Protocol = "vless+reality"
+	// ProtocolHysteria2 uses a self-signed certificate pinned by "pinSHA256" of the Method, and optionally
+	// the salamander obfuscation given by "obfs" and "obfs-password" of the Method.
+	ProtocolHysteria2 protocol.Protocol = "hysteria2"
+)
+
+// Hysteria2MsgHost is the host of the TCP requests of Hysteria2 carrying management messages.
+// The port is the MetadataCmd.
+const Hysteria2MsgHost = "msg.sweetlisa"
+
+// ProtocolValid reports if the protocol is supported, including the ones not defined by softwind.
+func ProtocolValid(p protocol.Protocol) bool {
+	switch p {
+	case ProtocolTrojan, ProtocolVLESSReality, ProtocolHysteria2:
+		return true
+	default:
+		return p.Valid()
+	}
+}
@@ -0,0 +1,88 @@
+package model
+
+import "fmt"
+
+const BucketRole = "role"
+
+var ErrPermissionDenied = fmt.Errorf("permission denied")
+
+// Role is the role of a member in a chat. A role has all permissions of the roles less than it.
+type Role int
+
+const (
+	RoleMember Role = iota
+	RoleOperator
+	RoleOwner
+	RoleINVALID
+)
+
+func (r Role) IsValid() bool {
+	return r >= 0 && r < RoleINVALID
+}
+
+func (r Role) String() string {
+	switch r {
+	case RoleMember:
+		return "member"
+	case RoleOperator:
+		return "operator"
+	case RoleOwner:
+		return "owner"
+	default:
+		return "invalid"
+	}
+}
+
+func ParseRole(s string) (Role, error) {
+	for r := RoleMember; r < RoleINVALID; r++ {
+		if r.String() == s {
+			return r, nil
+		}
+	}
+	return RoleINVALID, fmt.Errorf("unexpected role: %v", s)
+}
+
+// ChatRoles is the roles of members of a chat.
+type ChatRoles struct {
+	ChatIdentifier string
+	// Roles is the mapping from members to their roles. Members not in it are RoleMember.
+	// Members are identified by their Telegram user IDs in the group. Channels cannot have roles because
+	// Telegram does not tell who posts in a channel.
+	Roles map[string]Role
+}
+
+// Enforced reports if the roles are enforced. They are enforced once the chat has an owner, and everyone
+// is regarded as an owner before that.
+func (r *ChatRoles) Enforced() bool {
+	for _, role := range r.Roles {
+		if role == RoleOwner {
+			return true
+		}
+	}
+	return false
+}
+
+// GetRole returns the role of the member. Anonymous members, whose identifier is empty, are RoleMember
+// if the roles are enforced.
+func (r *ChatRoles) GetRole(member string) Role {
+	if !r.Enforced() {
+		return RoleOwner
+	}
+	if member == "" {
+		return RoleMember
+	}
+	if role, ok := r.Roles[member]; ok && role.IsValid() {
+		return role
+	}
+	return RoleMember
+}
+
+// CountRole returns the number of members of the role.
+func (r *ChatRoles) CountRole(role Role) (n int) {
+	for _, ro := range r.Roles {
+		if ro == role {
+			n++
+		}
+	}
+	return n
+}
@@ -32,6 +32,16 @@ type Server struct {
 	BandwidthLimit BandwidthLimit
 	// NoRelay is a flag to tell SweetLisa that the server do not want to be relayed
 	NoRelay bool
+	// NoRelayOverridden indicates NoRelay was set by the operator and will be kept across registrations
+	NoRelayOverridden bool `json:",omitempty"`
+	// Tags are the labels of the server such as regions, which can be referred to by the relay rules of others
+	Tags []string `json:",omitempty"`
+	// RelayAllow is the names or tags of servers (for a relay) or relays (for a server) that can be paired with.
+	// Empty means all.
+	RelayAllow []string `json:",omitempty"`
+	// RelayDeny is the names or tags of servers (for a relay) or relays (for a server) that cannot be paired with.
+	// It takes precedence over RelayAllow.
+	RelayDeny []string `json:",omitempty"`
 
 	// FailureCount is the number of consecutive failed pings
 	FailureCount int
@@ -41,6 +51,55 @@ type Server struct {
 	SyncNextSeen bool
 }
 
+// matchesAny reports if any rule refers to the server by its name or one of its tags.
+func (s *Server) matchesAny(rules []string) bool {
+	for _, rule := range rules {
+		if strings.EqualFold(rule, s.Name) {
+			return true
+		}
+		for _, tag := range s.Tags {
+			if strings.EqualFold(rule, tag) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Accepts reports if the relay rules of the server accept to be paired with the peer.
+func (s *Server) Accepts(peer *Server) bool {
+	if peer.matchesAny(s.RelayDeny) {
+		return false
+	}
+	return len(s.RelayAllow) == 0 || peer.matchesAny(s.RelayAllow)
+}
+
+// CanRelay reports if the relay can forward to the server according to NoRelay and relay rules of both sides.
+func CanRelay(relay *Server, server *Server) bool {
+	return !server.NoRelay && relay.Accepts(server) && server.Accepts(relay)
+}
+
+// ServerStatus is the status of a server or relay to show.
+type ServerStatus struct {
+	// Ticket is only shown to the verified operators
+	Ticket       string `json:",omitempty"`
+	Name         string
+	Type         TicketType
+	Tags         []string `json:",omitempty"`
+	Online       bool
+	NoRelay      bool
+	Exhausted    bool
+	FailureCount int
+	LastSeen     time.Time
+	SyncNextSeen bool
+	// RemainingKiB is the remaining bandwidth in the current cycle. It is nil if there is no limit.
+	RemainingKiB *int64 `json:",omitempty"`
+	// NextResetAt is the time to reset the bandwidth. It is nil if the bandwidth never resets.
+	NextResetAt *time.Time `json:",omitempty"`
+	// UpstreamRTTs is the mapping from names of upstream servers to the RTTs measured by the relay.
+	UpstreamRTTs map[string]time.Duration `json:",omitempty"`
+}
+
 type BandwidthLimit struct {
 	// Deprecated (only day is valid): ResetDay is the day of every month to reset the limit of bandwidth. Zero means never reset.
 	// This field should only be updated by SweetLisa after the first setup.
@@ -64,6 +123,13 @@ type BandwidthLimit struct {
 	UplinkInitialKiB int64 `json:",omitempty"`
 	// DownlinkInitialKiB is the DownlinkKiB at the beginning of the every cycles.
 	DownlinkInitialKiB int64 `json:",omitempty"`
+
+	// UplinkMbps is the uplink rate of the server in Mbps, which is a hint for the congestion control of clients.
+	// Zero means unknown.
+	UplinkMbps int64 `json:",omitempty"`
+	// DownlinkMbps is the downlink rate of the server in Mbps, which is a hint for the congestion control of clients.
+	// Zero means unknown.
+	DownlinkMbps int64 `json:",omitempty"`
 }
 
 func (l *BandwidthLimit) Exhausted() bool {
@@ -79,6 +145,43 @@ func (l *BandwidthLimit) Exhausted() bool {
 	return false
 }
 
+// UsedKiB returns the uplink and downlink usage in the current cycle.
+func (l *BandwidthLimit) UsedKiB() (uplink int64, downlink int64) {
+	return l.UplinkKiB - l.UplinkInitialKiB, l.DownlinkKiB - l.DownlinkInitialKiB
+}
+
+// RemainingKiB returns the remaining of the strictest limit in the current cycle. limited is false if there is no limit.
+func (l *BandwidthLimit) RemainingKiB() (remaining int64, limited bool) {
+	uplink, downlink := l.UsedKiB()
+	remainingList := make([]int64, 0, 2)
+	if l.TotalLimitGiB > 0 {
+		remainingList = append(remainingList, l.TotalLimitGiB*1000*1000-uplink-downlink)
+	}
+	if l.UplinkLimitGiB+l.DownlinkLimitGiB > 0 {
+		var r int64
+		if l.UplinkLimitGiB > 0 {
+			r += l.UplinkLimitGiB*1000*1000 - uplink
+		}
+		if l.DownlinkLimitGiB > 0 {
+			r += l.DownlinkLimitGiB*1000*1000 - downlink
+		}
+		remainingList = append(remainingList, r)
+	}
+	if len(remainingList) == 0 {
+		return 0, false
+	}
+	remaining = remainingList[0]
+	for _, r := range remainingList[1:] {
+		if r < remaining {
+			remaining = r
+		}
+	}
+	if remaining < 0 {
+		remaining = 0
+	}
+	return remaining, true
+}
+
 func (l *BandwidthLimit) Update(r BandwidthLimit) {
 	if l.ResetDay.IsZero() {
 		// (re-)initiate
@@ -103,6 +206,8 @@ func (l *BandwidthLimit) Update(r BandwidthLimit) {
 	l.TotalLimitGiB = r.TotalLimitGiB
 	l.DownlinkKiB = r.DownlinkKiB
 	l.UplinkKiB = r.UplinkKiB
+	l.UplinkMbps = r.UplinkMbps
+	l.DownlinkMbps = r.DownlinkMbps
 	if r.ResetDay.IsZero() {
 		l.ResetDay = time.Time{}
 	} else {
@@ -123,6 +228,26 @@ func (l *BandwidthLimit) IsTimeToReset() bool {
 	return false
 }
 
+// NextResetAt returns the time of the next reset. ok is false if the bandwidth never resets.
+// The returned time may be in the past if the reset is pending.
+func (l *BandwidthLimit) NextResetAt(now time.Time) (t time.Time, ok bool) {
+	if l.ResetDay.IsZero() {
+		return time.Time{}, false
+	}
+	loc := l.ResetDay.Location()
+	now = now.In(loc)
+	day := l.ResetDay.Day()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+	if l.ResetMonth == now.Month() {
+		month = month.AddDate(0, 1, 0)
+	}
+	// the reset day may not exist in short months, see IsTimeToReset
+	for month.AddDate(0, 1, -1).Day() < day {
+		month = month.AddDate(0, 1, 0)
+	}
+	return month.AddDate(0, 0, day-1), true
+}
+
 func (l *BandwidthLimit) Reset() {
 	l.UplinkInitialKiB = l.UplinkKiB
 	l.DownlinkInitialKiB = l.DownlinkKiB
@@ -138,17 +263,25 @@ func GetFirstHost(host string) string {
 	return fields[0]
 }
 
+// GetHosts splits the hosts and returns the non-empty ones.
+func GetHosts(hosts string) []string {
+	var list []string
+	for _, host := range strings.Split(hosts, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			list = append(list, host)
+		}
+	}
+	return list
+}
+
 func GetUserArgument(serverTicket, userTicket string, mngrArg Argument) Argument {
 	switch mngrArg.Protocol {
 	case protocol.ProtocolShadowsocks:
-		h := sha1.New()
-		h.Write([]byte(serverTicket))
-		h.Write([]byte(userTicket))
-		b := h.Sum(nil)
+		cipher := mngrArg.NegotiateUserCipher()
 		return Argument{
 			Protocol: mngrArg.Protocol,
-			Password: common.Base62Encoder.Encode(b)[:21],
-			Method:   "chacha20-ietf-poly1305",
+			Password: ShadowsocksPassword(cipher, serverTicket, userTicket),
+			Method:   cipher,
 		}
 	case protocol.ProtocolVMessTCP, protocol.ProtocolVMessTlsGrpc:
 		return Argument{
@@ -163,6 +296,25 @@ func GetUserArgument(serverTicket, userTicket string, mngrArg Argument) Argument
 			Password: common.StringToUUID5(serverTicket + ":" + userTicket),
 			Method:   mngrArg.Method,
 		}
+	case ProtocolTrojan:
+		return Argument{
+			Protocol: mngrArg.Protocol,
+			Password: common.StringToUUID5(serverTicket + "|trojan|" + userTicket),
+		}
+	case ProtocolVLESSReality:
+		return Argument{
+			Protocol:  mngrArg.Protocol,
+			Password:  common.StringToUUID5(serverTicket + "|vless|" + userTicket),
+			Method:    mngrArg.Method,
+			PublicKey: mngrArg.PublicKey,
+			ShortIds:  mngrArg.FirstShortId(),
+		}
+	case ProtocolHysteria2:
+		return Argument{
+			Protocol: mngrArg.Protocol,
+			Password: common.StringToUUID5(serverTicket + "|hysteria2|" + userTicket),
+			Method:   mngrArg.Method,
+		}
 	default:
 		return Argument{Protocol: protocol.ProtocolShadowsocks}
 	}
@@ -171,15 +323,11 @@ func GetUserArgument(serverTicket, userTicket string, mngrArg Argument) Argument
 func GetRelayUserArgument(serverTicket, relayTicket, userTicket string, mngrArg Argument) Argument {
 	switch mngrArg.Protocol {
 	case protocol.ProtocolShadowsocks:
-		h := sha1.New()
-		h.Write([]byte(serverTicket))
-		h.Write([]byte(relayTicket))
-		h.Write([]byte(userTicket))
-		b := h.Sum(nil)
+		cipher := mngrArg.NegotiateUserCipher()
 		return Argument{
 			Protocol: mngrArg.Protocol,
-			Password: common.Base62Encoder.Encode(b)[:21],
-			Method:   "chacha20-ietf-poly1305",
+			Password: ShadowsocksPassword(cipher, serverTicket, relayTicket, userTicket),
+			Method:   cipher,
 		}
 	case protocol.ProtocolVMessTCP, protocol.ProtocolVMessTlsGrpc:
 		return Argument{
@@ -194,11 +342,45 @@ func GetRelayUserArgument(serverTicket, relayTicket, userTicket string, mngrArg
 			Password: common.StringToUUID5(serverTicket + ":" + relayTicket + ":" + userTicket),
 			Method:   mngrArg.Method,
 		}
+	case ProtocolTrojan:
+		return Argument{
+			Protocol: mngrArg.Protocol,
+			Password: common.StringToUUID5(serverTicket + "|trojan|" + relayTicket + "|trojan|" + userTicket),
+		}
+	case ProtocolVLESSReality:
+		return Argument{
+			Protocol:  mngrArg.Protocol,
+			Password:  common.StringToUUID5(serverTicket + "|vless|" + relayTicket + "|vless|" + userTicket),
+			Method:    mngrArg.Method,
+			PublicKey: mngrArg.PublicKey,
+			ShortIds:  mngrArg.FirstShortId(),
+		}
+	case ProtocolHysteria2:
+		return Argument{
+			Protocol: mngrArg.Protocol,
+			Password: common.StringToUUID5(serverTicket + "|hysteria2|" + relayTicket + "|hysteria2|" + userTicket),
+			Method:   mngrArg.Method,
+		}
 	default:
 		return Argument{Protocol: protocol.ProtocolShadowsocks}
 	}
 }
 
+// GetChainUserArgument returns the argument for the client to connect to the first relay of the chain, which
+// forwards to the server through the rest relays in order. The client can be a user or the previous relay.
+// The chain of only one relay is compatible with GetRelayUserArgument.
+func GetChainUserArgument(serverTicket string, relayTickets []string, clientTicket string, mngrArg Argument) Argument {
+	if len(relayTickets) == 0 {
+		return GetUserArgument(serverTicket, clientTicket, mngrArg)
+	}
+	// regard the rest relays as a part of the server
+	key := serverTicket
+	for i := len(relayTickets) - 1; i >= 1; i-- {
+		key = relayTickets[i] + ">" + key
+	}
+	return GetRelayUserArgument(key, relayTickets[0], clientTicket, mngrArg)
+}
+
 type Argument struct {
 	// Required
 	Protocol protocol.Protocol `json:",omitempty"`
@@ -208,6 +390,18 @@ type Argument struct {
 	Password string `json:",omitempty"`
 	// Optional
 	Method string `json:",omitempty"`
+	// Optional. The REALITY x25519 public key in base64url without padding
+	PublicKey string `json:",omitempty"`
+	// Optional. The REALITY short IDs in hex (split by ","). Clients use the first one.
+	ShortIds string `json:",omitempty"`
+	// Optional. The shadowsocks ciphers accepted by the server for users (split by ",").
+	// Empty means chacha20-ietf-poly1305 only. The Method is still used to manage the server.
+	Ciphers string `json:",omitempty"`
+}
+
+// FirstShortId returns the short ID for clients to use.
+func (a Argument) FirstShortId() string {
+	return strings.TrimSpace(strings.SplitN(a.ShortIds, ",", 2)[0])
 }
 
 func (a Argument) Hash() string {
@@ -0,0 +1,66 @@
+package sharing_link
+
+import (
+	"gopkg.in/yaml.v3"
+)
+
+// ClashExporter is implemented by the sharing links that have an equivalent Clash proxy.
+type ClashExporter interface {
+	ExportToClash() *ClashProxy
+}
+
+type Clash struct {
+	Proxies     []*ClashProxy     `yaml:"proxies"`
+	ProxyGroups []ClashProxyGroup `yaml:"proxy-groups"`
+	Rules       []string          `yaml:"rules"`
+}
+
+type ClashProxy struct {
+	Name       string         `yaml:"name"`
+	Type       string         `yaml:"type"`
+	Server     string         `yaml:"server"`
+	Port       int            `yaml:"port"`
+	Cipher     string         `yaml:"cipher,omitempty"`
+	Password   string         `yaml:"password,omitempty"`
+	UUID       string         `yaml:"uuid,omitempty"`
+	AlterID    int            `yaml:"alterId,omitempty"`
+	Network    string         `yaml:"network,omitempty"`
+	TLS        bool           `yaml:"tls,omitempty"`
+	ServerName string         `yaml:"servername,omitempty"`
+	Sni        string         `yaml:"sni,omitempty"`
+	UDP        bool           `yaml:"udp,omitempty"`
+	GrpcOpts   *ClashGrpcOpts `yaml:"grpc-opts,omitempty"`
+
+	ClientFingerprint string            `yaml:"client-fingerprint,omitempty"`
+	RealityOpts       *ClashRealityOpts `yaml:"reality-opts,omitempty"`
+
+	// hysteria2
+	SkipCertVerify bool   `yaml:"skip-cert-verify,omitempty"`
+	Fingerprint    string `yaml:"fingerprint,omitempty"`
+	Obfs           string `yaml:"obfs,omitempty"`
+	ObfsPassword   string `yaml:"obfs-password,omitempty"`
+	Up             string `yaml:"up,omitempty"`
+	Down           string `yaml:"down,omitempty"`
+}
+
+type ClashGrpcOpts struct {
+	GrpcServiceName string `yaml:"grpc-service-name"`
+}
+
+type ClashRealityOpts struct {
+	PublicKey string `yaml:"public-key"`
+	ShortId   string `yaml:"short-id,omitempty"`
+}
+
+type ClashProxyGroup struct {
+	Name     string   `yaml:"name"`
+	Type     string   `yaml:"type"`
+	Proxies  []string `yaml:"proxies"`
+	URL      string   `yaml:"url,omitempty"`
+	Interval int      `yaml:"interval,omitempty"`
+}
+
+func (c Clash) ExportToString() string {
+	b, _ := yaml.Marshal(c)
+	return string(b)
+}
@@ -0,0 +1,109 @@
+package sharing_link
+
+import (
+	"net"
+	"net/url"
+	"strconv"
+
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/common"
+)
+
+type Hysteria2 struct {
+	Name     string
+	Server   string
+	Port     int
+	Password string
+	Sni      string
+	// Obfs is the obfuscation type such as "salamander". Empty means no obfuscation.
+	Obfs         string
+	ObfsPassword string
+	// PinSHA256 is the SHA-256 of the server certificate in hex
+	PinSHA256 string
+	// UpMbps and DownMbps are the bandwidth hints of the client. Zero means unknown.
+	UpMbps   int64
+	DownMbps int64
+}
+
+// insecure reports if the certificate should not be verified by the CA, because it is pinned.
+func (h *Hysteria2) insecure() bool {
+	return h.PinSHA256 != ""
+}
+
+func (h *Hysteria2) ExportToURL() string {
+	u := &url.URL{
+		Scheme:   "hysteria2",
+		User:     url.User(h.Password),
+		Host:     net.JoinHostPort(h.Server, strconv.Itoa(h.Port)),
+		Path:     "/",
+		Fragment: h.Name,
+	}
+	q := u.Query()
+	common.SetValue(&q, "sni", h.Sni)
+	if h.insecure() {
+		q.Set("insecure", "1")
+	}
+	common.SetValue(&q, "pinSHA256", h.PinSHA256)
+	if h.Obfs != "" {
+		q.Set("obfs", h.Obfs)
+		q.Set("obfs-password", h.ObfsPassword)
+	}
+	if h.UpMbps > 0 {
+		q.Set("upmbps", strconv.FormatInt(h.UpMbps, 10))
+	}
+	if h.DownMbps > 0 {
+		q.Set("downmbps", strconv.FormatInt(h.DownMbps, 10))
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func (h *Hysteria2) ExportToClash() *ClashProxy {
+	proxy := &ClashProxy{
+		Name:           h.Name,
+		Type:           "hysteria2",
+		Server:         h.Server,
+		Port:           h.Port,
+		Password:       h.Password,
+		Sni:            h.Sni,
+		SkipCertVerify: h.insecure(),
+		Fingerprint:    h.PinSHA256,
+		Obfs:           h.Obfs,
+		ObfsPassword:   h.ObfsPassword,
+		UDP:            true,
+	}
+	if h.UpMbps > 0 {
+		proxy.Up = strconv.FormatInt(h.UpMbps, 10) + " Mbps"
+	}
+	if h.DownMbps > 0 {
+		proxy.Down = strconv.FormatInt(h.DownMbps, 10) + " Mbps"
+	}
+	return proxy
+}
+
+// ExportToSingBox exports the outbound, which cannot pin the certificate and skips the verification instead.
+func (h *Hysteria2) ExportToSingBox() *SingBoxOutbound {
+	if h.insecure() {
+		// sing-box cannot pin the certificate, and skipping the verification is open to MITM
+		return nil
+	}
+	outbound := &SingBoxOutbound{
+		Type:       "hysteria2",
+		Tag:        h.Name,
+		Server:     h.Server,
+		ServerPort: h.Port,
+		Password:   h.Password,
+		UpMbps:     h.UpMbps,
+		DownMbps:   h.DownMbps,
+		TLS: &SingBoxTLS{
+			Enabled:    true,
+			ServerName: h.Sni,
+		},
+	}
+	if h.Obfs != "" {
+		outbound.Obfs = &SingBoxObfs{
+			Type:     h.Obfs,
+			Password: h.ObfsPassword,
+		}
+	}
+	return outbound
+}
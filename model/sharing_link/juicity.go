@@ -8,6 +8,7 @@ import (
 	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/common"
 )
 
+// Juicity is not a SingBoxExporter because sing-box has no juicity outbound.
 type Juicity struct {
 	Name                  string
 	Server                string
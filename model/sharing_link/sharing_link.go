@@ -0,0 +1,6 @@
+package sharing_link
+
+// URLExporter is implemented by the sharing links that can be exported to a URL.
+type URLExporter interface {
+	ExportToURL() string
+}
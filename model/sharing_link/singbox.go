@@ -0,0 +1,79 @@
+package sharing_link
+
+import (
+	jsoniter "github.com/json-iterator/go"
+)
+
+// SingBoxExporter is implemented by the sharing links that have an equivalent sing-box outbound.
+type SingBoxExporter interface {
+	ExportToSingBox() *SingBoxOutbound
+}
+
+type SingBox struct {
+	Outbounds []*SingBoxOutbound `json:"outbounds"`
+}
+
+type SingBoxOutbound struct {
+	Type       string `json:"type"`
+	Tag        string `json:"tag"`
+	Server     string `json:"server,omitempty"`
+	ServerPort int    `json:"server_port,omitempty"`
+
+	// shadowsocks
+	Method   string `json:"method,omitempty"`
+	Password string `json:"password,omitempty"`
+
+	// vmess and vless
+	UUID     string `json:"uuid,omitempty"`
+	Security string `json:"security,omitempty"`
+	AlterId  int    `json:"alter_id,omitempty"`
+
+	// hysteria2
+	UpMbps   int64        `json:"up_mbps,omitempty"`
+	DownMbps int64        `json:"down_mbps,omitempty"`
+	Obfs     *SingBoxObfs `json:"obfs,omitempty"`
+
+	TLS       *SingBoxTLS       `json:"tls,omitempty"`
+	Transport *SingBoxTransport `json:"transport,omitempty"`
+
+	// selector and urltest
+	Outbounds []string `json:"outbounds,omitempty"`
+	Default   string   `json:"default,omitempty"`
+	URL       string   `json:"url,omitempty"`
+	Interval  string   `json:"interval,omitempty"`
+}
+
+type SingBoxObfs struct {
+	Type     string `json:"type"`
+	Password string `json:"password"`
+}
+
+type SingBoxTLS struct {
+	Enabled    bool   `json:"enabled"`
+	ServerName string `json:"server_name,omitempty"`
+	Insecure   bool   `json:"insecure,omitempty"`
+
+	UTLS    *SingBoxUTLS    `json:"utls,omitempty"`
+	Reality *SingBoxReality `json:"reality,omitempty"`
+}
+
+type SingBoxUTLS struct {
+	Enabled     bool   `json:"enabled"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+type SingBoxReality struct {
+	Enabled   bool   `json:"enabled"`
+	PublicKey string `json:"public_key"`
+	ShortId   string `json:"short_id,omitempty"`
+}
+
+type SingBoxTransport struct {
+	Type        string `json:"type"`
+	ServiceName string `json:"service_name,omitempty"`
+}
+
+func (s SingBox) ExportToString() string {
+	b, _ := jsoniter.MarshalIndent(s, "", "  ")
+	return string(b)
+}
@@ -6,6 +6,8 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/common"
 )
 
 type SIP002 struct {
@@ -30,9 +32,63 @@ func (s *SIP002) ExportToURL() string {
 		q.Set("plugin", s.Plugin.String())
 		u.RawQuery = q.Encode()
 	}
+	if strings.HasPrefix(s.Cipher, "2022-") {
+		// the userinfo of AEAD-2022 ciphers must be percent-encoded instead of base64-encoded
+		u.User = nil
+		return "ss://" + url.PathEscape(s.Cipher) + ":" + url.QueryEscape(s.Password) + "@" + strings.TrimPrefix(u.String(), "ss://")
+	}
 	return u.String()
 }
 
+func (s *SIP002) ExportToClash() *ClashProxy {
+	if s.Plugin.Name != "" {
+		// plugins are not supported yet
+		return nil
+	}
+	return &ClashProxy{
+		Name:     s.Name,
+		Type:     "ss",
+		Server:   s.Server,
+		Port:     s.Port,
+		Cipher:   s.Cipher,
+		Password: s.Password,
+		UDP:      true,
+	}
+}
+
+func (s *SIP002) ExportToSingBox() *SingBoxOutbound {
+	if s.Plugin.Name != "" {
+		// plugins are not supported yet
+		return nil
+	}
+	return &SingBoxOutbound{
+		Type:       "shadowsocks",
+		Tag:        s.Name,
+		Server:     s.Server,
+		ServerPort: s.Port,
+		Method:     s.Cipher,
+		Password:   s.Password,
+	}
+}
+
+// ExportToSIP008Server exports the SIP008 server with an id that is stable across the quota changes in name.
+func (s *SIP002) ExportToSIP008Server() *SIP008Server {
+	address := net.JoinHostPort(s.Server, strconv.Itoa(s.Port))
+	server := &SIP008Server{
+		Id:         common.StringToUUID5(address + ":" + s.Cipher + ":" + s.Password),
+		Remarks:    s.Name,
+		Server:     s.Server,
+		ServerPort: s.Port,
+		Password:   s.Password,
+		Method:     s.Cipher,
+	}
+	if s.Plugin.Name != "" {
+		server.Plugin = s.Plugin.Name
+		server.PluginOpts = strings.TrimPrefix(s.Plugin.String(), s.Plugin.Name+";")
+	}
+	return server
+}
+
 type SIP003 struct {
 	Name string     `json:"name"`
 	Opts SIP003Opts `json:"opts"`
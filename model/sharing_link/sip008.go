@@ -2,11 +2,16 @@ package sharing_link
 
 import jsoniter "github.com/json-iterator/go"
 
+// SIP008Exporter is implemented by the sharing links that have an equivalent SIP008 server.
+type SIP008Exporter interface {
+	ExportToSIP008Server() *SIP008Server
+}
+
 type SIP008 struct {
 	Version        int            `json:"version"`
 	Servers        []SIP008Server `json:"servers"`
-	BytesUsed      int64          `json:"bytes_used"`
-	BytesRemaining int64          `json:"bytes_remaining"`
+	BytesUsed      *int64         `json:"bytes_used,omitempty"`
+	BytesRemaining *int64         `json:"bytes_remaining,omitempty"`
 }
 
 type SIP008Server struct {
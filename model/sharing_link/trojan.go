@@ -0,0 +1,62 @@
+package sharing_link
+
+import (
+	"net"
+	"net/url"
+	"strconv"
+
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/common"
+)
+
+type Trojan struct {
+	Name          string
+	Server        string
+	Port          int
+	Password      string
+	Sni           string
+	AllowInsecure bool
+}
+
+func (t *Trojan) ExportToURL() string {
+	u := &url.URL{
+		Scheme:   "trojan",
+		User:     url.User(t.Password),
+		Host:     net.JoinHostPort(t.Server, strconv.Itoa(t.Port)),
+		Fragment: t.Name,
+	}
+	q := u.Query()
+	if t.AllowInsecure {
+		q.Set("allowInsecure", "1")
+	}
+	common.SetValue(&q, "sni", t.Sni)
+	q.Set("type", "tcp")
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func (t *Trojan) ExportToClash() *ClashProxy {
+	return &ClashProxy{
+		Name:     t.Name,
+		Type:     "trojan",
+		Server:   t.Server,
+		Port:     t.Port,
+		Password: t.Password,
+		Sni:      t.Sni,
+		UDP:      true,
+	}
+}
+
+func (t *Trojan) ExportToSingBox() *SingBoxOutbound {
+	return &SingBoxOutbound{
+		Type:       "trojan",
+		Tag:        t.Name,
+		Server:     t.Server,
+		ServerPort: t.Port,
+		Password:   t.Password,
+		TLS: &SingBoxTLS{
+			Enabled:    true,
+			ServerName: t.Sni,
+			Insecure:   t.AllowInsecure,
+		},
+	}
+}
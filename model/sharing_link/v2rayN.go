@@ -3,6 +3,7 @@ package sharing_link
 import (
 	"encoding/base64"
 	jsoniter "github.com/json-iterator/go"
+	"strconv"
 	"strings"
 )
 
@@ -26,3 +27,70 @@ func (v *V2RayN) ExportToURL() string {
 	b, _ := jsoniter.Marshal(v)
 	return "vmess://" + strings.TrimSuffix(base64.StdEncoding.EncodeToString(b), "=")
 }
+
+func (v *V2RayN) ExportToClash() *ClashProxy {
+	port, err := strconv.Atoi(v.Port)
+	if err != nil {
+		return nil
+	}
+	aid, _ := strconv.Atoi(v.Aid)
+	p := &ClashProxy{
+		Name:    v.Ps,
+		Type:    "vmess",
+		Server:  v.Add,
+		Port:    port,
+		Cipher:  "auto",
+		UUID:    v.ID,
+		AlterID: aid,
+		UDP:     true,
+	}
+	if v.TLS == "tls" {
+		p.TLS = true
+		p.ServerName = v.Sni
+	}
+	switch v.Net {
+	case "grpc":
+		p.Network = "grpc"
+		p.GrpcOpts = &ClashGrpcOpts{GrpcServiceName: v.Path}
+	case "tcp", "":
+	default:
+		// other transports are not used by SweetLisa
+		return nil
+	}
+	return p
+}
+
+func (v *V2RayN) ExportToSingBox() *SingBoxOutbound {
+	port, err := strconv.Atoi(v.Port)
+	if err != nil {
+		return nil
+	}
+	aid, _ := strconv.Atoi(v.Aid)
+	o := &SingBoxOutbound{
+		Type:       "vmess",
+		Tag:        v.Ps,
+		Server:     v.Add,
+		ServerPort: port,
+		UUID:       v.ID,
+		Security:   "auto",
+		AlterId:    aid,
+	}
+	if v.TLS == "tls" {
+		o.TLS = &SingBoxTLS{
+			Enabled:    true,
+			ServerName: v.Sni,
+		}
+	}
+	switch v.Net {
+	case "grpc":
+		o.Transport = &SingBoxTransport{
+			Type:        "grpc",
+			ServiceName: v.Path,
+		}
+	case "tcp", "":
+	default:
+		// other transports are not used by SweetLisa
+		return nil
+	}
+	return o
+}
@@ -0,0 +1,89 @@
+package sharing_link
+
+import (
+	"net"
+	"net/url"
+	"strconv"
+
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/common"
+)
+
+// VLESSReality is the VLESS over REALITY, which is shared in the format of Xray.
+type VLESSReality struct {
+	Name        string
+	Server      string
+	Port        int
+	ID          string
+	Sni         string
+	Fingerprint string
+	PublicKey   string
+	ShortId     string
+}
+
+func (v *VLESSReality) fingerprint() string {
+	if v.Fingerprint == "" {
+		return "chrome"
+	}
+	return v.Fingerprint
+}
+
+func (v *VLESSReality) ExportToURL() string {
+	u := &url.URL{
+		Scheme:   "vless",
+		User:     url.User(v.ID),
+		Host:     net.JoinHostPort(v.Server, strconv.Itoa(v.Port)),
+		Fragment: v.Name,
+	}
+	q := u.Query()
+	q.Set("encryption", "none")
+	q.Set("security", "reality")
+	q.Set("type", "tcp")
+	common.SetValue(&q, "sni", v.Sni)
+	q.Set("fp", v.fingerprint())
+	q.Set("pbk", v.PublicKey)
+	common.SetValue(&q, "sid", v.ShortId)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func (v *VLESSReality) ExportToClash() *ClashProxy {
+	return &ClashProxy{
+		Name:              v.Name,
+		Type:              "vless",
+		Server:            v.Server,
+		Port:              v.Port,
+		UUID:              v.ID,
+		Network:           "tcp",
+		TLS:               true,
+		ServerName:        v.Sni,
+		UDP:               true,
+		ClientFingerprint: v.fingerprint(),
+		RealityOpts: &ClashRealityOpts{
+			PublicKey: v.PublicKey,
+			ShortId:   v.ShortId,
+		},
+	}
+}
+
+func (v *VLESSReality) ExportToSingBox() *SingBoxOutbound {
+	return &SingBoxOutbound{
+		Type:       "vless",
+		Tag:        v.Name,
+		Server:     v.Server,
+		ServerPort: v.Port,
+		UUID:       v.ID,
+		TLS: &SingBoxTLS{
+			Enabled:    true,
+			ServerName: v.Sni,
+			UTLS: &SingBoxUTLS{
+				Enabled:     true,
+				Fingerprint: v.fingerprint(),
+			},
+			Reality: &SingBoxReality{
+				Enabled:   true,
+				PublicKey: v.PublicKey,
+				ShortId:   v.ShortId,
+			},
+		},
+	}
+}
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+)
+
+const (
+	BucketSubDomain = "subdomain"
+	// MaxSubDomainRemoveAttempts is the number of failed attempts to remove the records before it is reported
+	// in the feed. The removal is still retried after that.
+	MaxSubDomainRemoveAttempts = 6
+)
+
+// SubDomain is the subdomain assigned to servers for TLS, which should be removed after the servers are gone.
+// The subdomain is derived from the IP, thus servers with the same IP share it.
+type SubDomain struct {
+	Domain string
+	// ServerTickets are the tickets of the servers sharing the subdomain
+	ServerTickets []string
+	// ServerName and ChatIdentifier are of the server assigned last
+	ServerName     string
+	ChatIdentifier string
+	AssignedAt     time.Time
+	// Removing indicates all the servers were revoked or purged, and the records are waiting to be removed.
+	Removing bool `json:",omitempty"`
+	// RemoveAttempts is the number of failed attempts to remove the records
+	RemoveAttempts int    `json:",omitempty"`
+	LastError      string `json:",omitempty"`
+}
@@ -0,0 +1,35 @@
+package model
+
+// Subscription is the protocol-independent subscription of a ticket, which is rendered by renderers.
+type Subscription struct {
+	Ticket Ticket
+	// Endpoints are nodes connecting to the endpoint servers directly
+	Endpoints []SubscriptionNode
+	// Relays are nodes connecting to the endpoint servers through relays
+	Relays []SubscriptionNode
+	// Servers are the servers and relays in the chat, which are used to calculate the quota
+	Servers []Server
+}
+
+// Nodes returns endpoints followed by relays.
+func (s *Subscription) Nodes() []SubscriptionNode {
+	nodes := make([]SubscriptionNode, 0, len(s.Endpoints)+len(s.Relays))
+	nodes = append(nodes, s.Endpoints...)
+	return append(nodes, s.Relays...)
+}
+
+type SubscriptionNode struct {
+	// Name is the name to show
+	Name string
+	Host string
+	Port int
+	// Argument is the user argument to connect to the node
+	Argument Argument
+	// Sni is the TLS server name of the node. It is empty for protocols without TLS.
+	Sni string
+	// Chain is the names of servers from the node to the endpoint server
+	Chain []string
+	// UpMbps and DownMbps are the bandwidth hints of the node from the view of the client. Zero means unknown.
+	UpMbps   int64
+	DownMbps int64
+}
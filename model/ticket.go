@@ -26,4 +26,16 @@ type Ticket struct {
 	ChatIdentifier string
 	Type           TicketType
 	ExpireAt       time.Time
+	// QuotaGiB is the monthly traffic quota of the user ticket in GB. Zero means no limit.
+	QuotaGiB int64 `json:",omitempty"`
+	// Issuer is the member who issued the ticket. Empty means anonymous.
+	Issuer string `json:",omitempty"`
+}
+
+// Masked returns the ticket that can be shown in public.
+func (t *Ticket) Masked() string {
+	if len(t.Ticket) <= 6 {
+		return "***"
+	}
+	return t.Ticket[:6] + "***"
 }
@@ -0,0 +1,64 @@
+package model
+
+import (
+	"time"
+)
+
+const (
+	BucketUsage     = "usage"
+	MaxUsageHistory = 12
+)
+
+type MonthlyUsage struct {
+	// Month is the beginning of the monthly cycle, which starts at the UsageResetDay of the chat
+	Month       time.Time
+	UplinkKiB   int64
+	DownlinkKiB int64
+}
+
+func (u *MonthlyUsage) TotalKiB() int64 {
+	return u.UplinkKiB + u.DownlinkKiB
+}
+
+// TicketUsage is the traffic of a user ticket across all servers and relays in the chat.
+type TicketUsage struct {
+	Ticket         string
+	ChatIdentifier string
+	MonthlyUsage
+	// History is the usage of previous months, from new to old
+	History []MonthlyUsage `json:",omitempty"`
+	// CutOff indicates the passages of the ticket have been removed because of exceeding the quota
+	CutOff bool `json:",omitempty"`
+}
+
+// BeginningOfCycle returns the beginning of the monthly cycle that t is in.
+func BeginningOfCycle(t time.Time, resetDay int) time.Time {
+	if resetDay < 1 || resetDay > MaxUsageResetDay {
+		resetDay = 1
+	}
+	beginning := time.Date(t.Year(), t.Month(), resetDay, 0, 0, 0, 0, t.Location())
+	if t.Before(beginning) {
+		beginning = beginning.AddDate(0, -1, 0)
+	}
+	return beginning
+}
+
+// Exceeded reports if the usage of the current cycle exceeds given quota. Zero quota means no limit.
+func (u *TicketUsage) Exceeded(quotaGiB int64) bool {
+	return quotaGiB > 0 && u.TotalKiB() >= quotaGiB*1000*1000
+}
+
+// Roll archives the usage of the current cycle into the history if the cycle has passed.
+func (u *TicketUsage) Roll(now time.Time, resetDay int) {
+	month := BeginningOfCycle(now, resetDay)
+	if u.Month.Equal(month) {
+		return
+	}
+	if !u.Month.IsZero() {
+		u.History = append([]MonthlyUsage{u.MonthlyUsage}, u.History...)
+		if len(u.History) > MaxUsageHistory {
+			u.History = u.History[:MaxUsageHistory]
+		}
+	}
+	u.MonthlyUsage = MonthlyUsage{Month: month}
+}
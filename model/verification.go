@@ -14,6 +14,10 @@ type Verification struct {
 	ExpireAt       time.Time
 	ChatIdentifier string
 	Progress       VerificationProgress
+	// Member is the member who passed the verification. Empty means anonymous.
+	Member string `json:",omitempty"`
+	// Role is the role of the member when the verification passed.
+	Role Role `json:",omitempty"`
 }
 
 type VerificationProgress int
@@ -0,0 +1,193 @@
+package acme
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/pkg/log"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/pkg/nameserver"
+	"golang.org/x/crypto/acme"
+)
+
+// Client obtains certificates by the ACME DNS-01 challenge, whose TXT records are set by the nameserver.
+type Client struct {
+	client *acme.Client
+	ns     nameserver.Nameserver
+	// Resolver is used to wait for the propagation of TXT records. Nil means net.DefaultResolver.
+	Resolver *net.Resolver
+	// PropagationTimeout is the maximum time to wait for the propagation. The challenge will be accepted anyway
+	// after the timeout because the resolver may be different from the ones of the CA.
+	PropagationTimeout time.Duration
+}
+
+type Certificate struct {
+	// CertificatePEM is the certificate chain in PEM
+	CertificatePEM []byte
+	// PrivateKeyPEM is the private key of the certificate in PEM
+	PrivateKeyPEM []byte
+	NotAfter      time.Time
+}
+
+func NewClient(directoryURL string, accountKey crypto.Signer, ns nameserver.Nameserver) *Client {
+	return &Client{
+		client: &acme.Client{
+			Key:          accountKey,
+			DirectoryURL: directoryURL,
+		},
+		ns:                 ns,
+		PropagationTimeout: 2 * time.Minute,
+	}
+}
+
+// Register registers the account if it does not exist. The email is optional.
+func (c *Client) Register(ctx context.Context, email string) error {
+	var account acme.Account
+	if email != "" {
+		account.Contact = []string{"mailto:" + email}
+	}
+	if _, err := c.client.Register(ctx, &account, acme.AcceptTOS); err != nil && !errors.Is(err, acme.ErrAccountAlreadyExists) {
+		return err
+	}
+	return nil
+}
+
+func (c *Client) waitPropagation(ctx context.Context, domain string, value string) {
+	resolver := c.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.PropagationTimeout)
+	defer cancel()
+	for {
+		records, _ := resolver.LookupTXT(ctx, domain)
+		for _, record := range records {
+			if record == value {
+				return
+			}
+		}
+		select {
+		case <-ctx.Done():
+			log.Warn("acme: timeout for waiting for TXT record of %v", domain)
+			return
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+// authorize fulfills the DNS-01 challenge of the authorization.
+func (c *Client) authorize(ctx context.Context, authzURL string) error {
+	authz, err := c.client.GetAuthorization(ctx, authzURL)
+	if err != nil {
+		return err
+	}
+	if authz.Status == acme.StatusValid {
+		return nil
+	}
+	var chal *acme.Challenge
+	for _, ch := range authz.Challenges {
+		if ch.Type == "dns-01" {
+			chal = ch
+			break
+		}
+	}
+	if chal == nil {
+		return fmt.Errorf("no dns-01 challenge for %v", authz.Identifier.Value)
+	}
+	value, err := c.client.DNS01ChallengeRecord(chal.Token)
+	if err != nil {
+		return err
+	}
+	domain := "_acme-challenge." + authz.Identifier.Value
+	if err = c.ns.AddTXT(ctx, domain, value); err != nil {
+		return fmt.Errorf("AddTXT: %w", err)
+	}
+	defer func() {
+		// the ctx may be canceled
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+		if e := c.ns.RemoveTXT(ctx, domain, value); e != nil {
+			log.Warn("acme: RemoveTXT: %v", e)
+		}
+	}()
+	c.waitPropagation(ctx, domain, value)
+	if _, err = c.client.Accept(ctx, chal); err != nil {
+		return err
+	}
+	if _, err = c.client.WaitAuthorization(ctx, authz.URI); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Obtain issues a certificate for the domains.
+func (c *Client) Obtain(ctx context.Context, domains ...string) (cert *Certificate, err error) {
+	order, err := c.client.AuthorizeOrder(ctx, acme.DomainIDs(domains...))
+	if err != nil {
+		return nil, err
+	}
+	for _, authzURL := range order.AuthzURLs {
+		if err = c.authorize(ctx, authzURL); err != nil {
+			return nil, err
+		}
+	}
+	if order, err = c.client.WaitOrder(ctx, order.URI); err != nil {
+		return nil, err
+	}
+	key, err := GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{DNSNames: domains}, key)
+	if err != nil {
+		return nil, err
+	}
+	der, _, err := c.client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(der) == 0 {
+		return nil, fmt.Errorf("empty certificate chain")
+	}
+	leaf, err := x509.ParseCertificate(der[0])
+	if err != nil {
+		return nil, err
+	}
+	cert = &Certificate{NotAfter: leaf.NotAfter}
+	for _, b := range der {
+		cert.CertificatePEM = append(cert.CertificatePEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: b})...)
+	}
+	if cert.PrivateKeyPEM, err = MarshalPrivateKey(key); err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
+
+// GenerateKey generates an ECDSA P-256 key, which can be used as the account key.
+func GenerateKey() (*ecdsa.PrivateKey, error) {
+	return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+}
+
+func MarshalPrivateKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	b, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: b}), nil
+}
+
+func ParsePrivateKey(b []byte) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("invalid private key")
+	}
+	return x509.ParseECPrivateKey(block.Bytes)
+}
@@ -0,0 +1,371 @@
+package acme
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeCA is an in-process ACME server implementing the minimal RFC 8555 flow of the DNS-01 challenge.
+// The JWS of requests is decoded but not verified.
+type fakeCA struct {
+	*httptest.Server
+	t *testing.T
+
+	mu sync.Mutex
+	// authorized is the status of the authorization of every domain
+	authorized map[string]bool
+	domains    []string
+	// failAccept makes the CA reject the challenge responses
+	failAccept bool
+	accepted   int
+	// csr is the CSR of the finalized order
+	csr []byte
+
+	caKey  interface{}
+	caCert *x509.Certificate
+}
+
+func newFakeCA(t *testing.T) *fakeCA {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fake CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca := &fakeCA{
+		t:          t,
+		authorized: make(map[string]bool),
+		caKey:      key,
+		caCert:     caCert,
+	}
+	ca.Server = httptest.NewServer(http.HandlerFunc(ca.serve))
+	t.Cleanup(ca.Close)
+	return ca
+}
+
+func (ca *fakeCA) reply(w http.ResponseWriter, status int, location string, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if location != "" {
+		w.Header().Set("Location", ca.URL+location)
+	}
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// payload returns the decoded payload of the JWS in the request body.
+func payload(r *http.Request) ([]byte, error) {
+	var jws struct {
+		Payload string `json:"payload"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&jws); err != nil {
+		return nil, err
+	}
+	return base64.RawURLEncoding.DecodeString(jws.Payload)
+}
+
+func (ca *fakeCA) order() map[string]interface{} {
+	status := "ready"
+	var identifiers []map[string]string
+	var authorizations []string
+	for _, domain := range ca.domains {
+		identifiers = append(identifiers, map[string]string{"type": "dns", "value": domain})
+		authorizations = append(authorizations, ca.URL+"/authz/"+domain)
+		if !ca.authorized[domain] {
+			status = "pending"
+		}
+	}
+	return map[string]interface{}{
+		"status":         status,
+		"identifiers":    identifiers,
+		"authorizations": authorizations,
+		"finalize":       ca.URL + "/finalize",
+	}
+}
+
+func (ca *fakeCA) authz(domain string) map[string]interface{} {
+	status := "pending"
+	if ca.authorized[domain] {
+		status = "valid"
+	}
+	return map[string]interface{}{
+		"status":     status,
+		"identifier": map[string]string{"type": "dns", "value": domain},
+		"challenges": []map[string]string{
+			{"type": "http-01", "url": ca.URL + "/chal/http/" + domain, "token": "http-" + domain, "status": "pending"},
+			{"type": "dns-01", "url": ca.URL + "/chal/dns/" + domain, "token": "dns-" + domain, "status": status},
+		},
+	}
+}
+
+func (ca *fakeCA) issue(csrDER []byte) ([]byte, error) {
+	csr, err := x509.ParseCertificateRequest(csrDER)
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		DNSNames:     csr.DNSNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(90 * 24 * time.Hour).Truncate(time.Second),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.caCert, csr.PublicKey, ca.caKey)
+	if err != nil {
+		return nil, err
+	}
+	chain := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	return append(chain, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.caCert.Raw})...), nil
+}
+
+func (ca *fakeCA) serve(w http.ResponseWriter, r *http.Request) {
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+	w.Header().Set("Replay-Nonce", fmt.Sprintf("nonce-%d", time.Now().UnixNano()))
+	if r.URL.Path == "/directory" {
+		ca.reply(w, http.StatusOK, "", map[string]string{
+			"newNonce":   ca.URL + "/new-nonce",
+			"newAccount": ca.URL + "/new-account",
+			"newOrder":   ca.URL + "/new-order",
+		})
+		return
+	}
+	if r.URL.Path == "/new-nonce" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	body, err := payload(r)
+	if err != nil {
+		ca.t.Errorf("%v: %v", r.URL.Path, err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	switch path := r.URL.Path; {
+	case path == "/new-account":
+		ca.reply(w, http.StatusCreated, "/account", map[string]string{"status": "valid"})
+	case path == "/new-order":
+		var req struct {
+			Identifiers []struct{ Value string }
+		}
+		if err = json.Unmarshal(body, &req); err != nil {
+			ca.t.Errorf("%v: %v", path, err)
+		}
+		ca.domains = nil
+		for _, id := range req.Identifiers {
+			ca.domains = append(ca.domains, id.Value)
+		}
+		ca.reply(w, http.StatusCreated, "/order", ca.order())
+	case path == "/order":
+		ca.reply(w, http.StatusOK, "/order", ca.order())
+	case strings.HasPrefix(path, "/authz/"):
+		ca.reply(w, http.StatusOK, "", ca.authz(strings.TrimPrefix(path, "/authz/")))
+	case strings.HasPrefix(path, "/chal/dns/"):
+		domain := strings.TrimPrefix(path, "/chal/dns/")
+		ca.accepted++
+		if ca.failAccept {
+			ca.reply(w, http.StatusForbidden, "", map[string]interface{}{
+				"type":   "urn:ietf:params:acme:error:unauthorized",
+				"detail": "rejected by the test",
+				"status": http.StatusForbidden,
+			})
+			return
+		}
+		ca.authorized[domain] = true
+		ca.reply(w, http.StatusOK, "", map[string]string{"type": "dns-01", "url": ca.URL + path, "token": "dns-" + domain, "status": "valid"})
+	case path == "/finalize":
+		var req struct {
+			CSR string `json:"csr"`
+		}
+		if err = json.Unmarshal(body, &req); err != nil {
+			ca.t.Errorf("%v: %v", path, err)
+		}
+		csr, err := base64.RawURLEncoding.DecodeString(req.CSR)
+		if err != nil {
+			ca.t.Errorf("%v: %v", path, err)
+		}
+		if _, err = x509.ParseCertificateRequest(csr); err != nil {
+			ca.t.Errorf("%v: %v", path, err)
+		}
+		ca.csr = csr
+		order := ca.order()
+		order["status"] = "valid"
+		order["certificate"] = ca.URL + "/cert"
+		ca.reply(w, http.StatusOK, "/order", order)
+	case path == "/cert":
+		chain, err := ca.issue(ca.csr)
+		if err != nil {
+			ca.t.Errorf("%v: %v", path, err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/pem-certificate-chain")
+		_, _ = w.Write(chain)
+	default:
+		ca.t.Errorf("unexpected request: %v %v", r.Method, path)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+type txtRecord struct {
+	domain string
+	value  string
+}
+
+// fakeNameserver records the TXT records added and removed.
+type fakeNameserver struct {
+	mu      sync.Mutex
+	added   []txtRecord
+	removed []txtRecord
+}
+
+func (ns *fakeNameserver) Assign(ctx context.Context, domain string, ip string) error {
+	return errors.New("not implemented")
+}
+
+func (ns *fakeNameserver) RemoveRecords(ctx context.Context, domain string) error {
+	return errors.New("not implemented")
+}
+
+func (ns *fakeNameserver) AddTXT(ctx context.Context, domain string, value string) error {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	ns.added = append(ns.added, txtRecord{domain: domain, value: value})
+	return nil
+}
+
+func (ns *fakeNameserver) RemoveTXT(ctx context.Context, domain string, value string) error {
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+	ns.removed = append(ns.removed, txtRecord{domain: domain, value: value})
+	return nil
+}
+
+func newTestClient(t *testing.T, ca *fakeCA, ns *fakeNameserver) *Client {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient(ca.URL+"/directory", key, ns)
+	// no nameserver is reachable in tests
+	c.Resolver = &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			return nil, errors.New("no network")
+		},
+	}
+	c.PropagationTimeout = 100 * time.Millisecond
+	if err = c.Register(context.Background(), "admin@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestObtain(t *testing.T) {
+	ca := newFakeCA(t)
+	ns := &fakeNameserver{}
+	c := newTestClient(t, ca, ns)
+	cert, err := c.Obtain(context.Background(), "a.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := c.client.DNS01ChallengeRecord("dns-a.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []txtRecord{{domain: "_acme-challenge.a.example.com", value: value}}
+	if fmt.Sprint(ns.added) != fmt.Sprint(want) {
+		t.Fatalf("added TXT records: got %v, want %v", ns.added, want)
+	}
+	if fmt.Sprint(ns.removed) != fmt.Sprint(want) {
+		t.Fatalf("removed TXT records: got %v, want %v", ns.removed, want)
+	}
+
+	block, rest := pem.Decode(cert.CertificatePEM)
+	if block == nil {
+		t.Fatal("no certificate in the chain")
+	}
+	if next, _ := pem.Decode(rest); next == nil {
+		t.Fatal("the chain does not contain the issuer")
+	}
+	leaf, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = leaf.VerifyHostname("a.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if !cert.NotAfter.Equal(leaf.NotAfter) {
+		t.Fatalf("NotAfter: got %v, want %v", cert.NotAfter, leaf.NotAfter)
+	}
+	key, err := ParsePrivateKey(cert.PrivateKeyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !key.PublicKey.Equal(leaf.PublicKey) {
+		t.Fatal("the private key does not match the certificate")
+	}
+}
+
+func TestObtainValidAuthorization(t *testing.T) {
+	ca := newFakeCA(t)
+	ca.authorized["a.example.com"] = true
+	ns := &fakeNameserver{}
+	c := newTestClient(t, ca, ns)
+	if _, err := c.Obtain(context.Background(), "a.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if len(ns.added) != 0 || len(ns.removed) != 0 {
+		t.Fatalf("unexpected TXT records: added %v, removed %v", ns.added, ns.removed)
+	}
+	if ca.accepted != 0 {
+		t.Fatalf("the challenge was accepted %v times", ca.accepted)
+	}
+}
+
+func TestObtainRemovesTXTWhenAcceptFails(t *testing.T) {
+	ca := newFakeCA(t)
+	ca.failAccept = true
+	ns := &fakeNameserver{}
+	c := newTestClient(t, ca, ns)
+	if _, err := c.Obtain(context.Background(), "a.example.com"); err == nil {
+		t.Fatal("expected an error")
+	}
+	if ca.accepted != 1 {
+		t.Fatalf("the challenge was accepted %v times", ca.accepted)
+	}
+	if len(ns.added) != 1 {
+		t.Fatalf("added TXT records: %v", ns.added)
+	}
+	if fmt.Sprint(ns.removed) != fmt.Sprint(ns.added) {
+		t.Fatalf("removed TXT records: got %v, want %v", ns.removed, ns.added)
+	}
+}
@@ -95,15 +95,61 @@ func (c *Cloudflare) RemoveRecords(ctx context.Context, domain string) error {
 		return err
 	}
 	var wg sync.WaitGroup
+	var mu sync.Mutex
 	for _, record := range records {
 		wg.Add(1)
 		go func(id string) {
 			defer wg.Done()
 			e := c.api.DeleteDNSRecord(ctx, zoneID, id)
 			if e != nil {
+				mu.Lock()
 				err = e
+				mu.Unlock()
 			}
 		}(record.ID)
 	}
+	wg.Wait()
 	return err
 }
+
+func (c *Cloudflare) zoneID(domain string) (string, error) {
+	fields := strings.Split(domain, ".")
+	if len(fields) < 2 {
+		return "", fmt.Errorf("invalid domain: %v", domain)
+	}
+	return c.api.ZoneIDByName(strings.Join(fields[len(fields)-2:], "."))
+}
+
+func (c *Cloudflare) AddTXT(ctx context.Context, domain string, value string) error {
+	zoneID, err := c.zoneID(domain)
+	if err != nil {
+		return err
+	}
+	_, err = c.api.CreateDNSRecord(ctx, zoneID, cloudflare.DNSRecord{
+		Type:    "TXT",
+		Name:    domain,
+		Content: value,
+		TTL:     60,
+	})
+	return err
+}
+
+func (c *Cloudflare) RemoveTXT(ctx context.Context, domain string, value string) error {
+	zoneID, err := c.zoneID(domain)
+	if err != nil {
+		return err
+	}
+	records, err := c.api.DNSRecords(ctx, zoneID, cloudflare.DNSRecord{Name: domain, Type: "TXT"})
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		if record.Content != value {
+			continue
+		}
+		if err = c.api.DeleteDNSRecord(ctx, zoneID, record.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
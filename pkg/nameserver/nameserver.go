@@ -8,6 +8,10 @@ import (
 type Nameserver interface {
 	Assign(ctx context.Context, domain string, ip string) error
 	RemoveRecords(ctx context.Context, domain string) error
+	// AddTXT adds a TXT record to the domain, which is used by the ACME DNS-01 challenge.
+	AddTXT(ctx context.Context, domain string, value string) error
+	// RemoveTXT removes the TXT records of the domain with the value.
+	RemoveTXT(ctx context.Context, domain string, value string) error
 }
 
 type Creator func(token string) (Nameserver, error)
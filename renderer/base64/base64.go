@@ -0,0 +1,38 @@
+package base64
+
+import (
+	b64 "encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/model"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/model/sharing_link"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/renderer"
+)
+
+func init() {
+	renderer.Register("base64", Base64{})
+}
+
+// Base64 renders the sharing links split by lines and encoded in base64.
+type Base64 struct{}
+
+func (Base64) ContentType() string {
+	return "text/plain; charset=utf-8"
+}
+
+func (Base64) Render(sub *model.Subscription) ([]byte, error) {
+	alert := sharing_link.SIP002{
+		Name:     fmt.Sprintf("ExpireAt: %v", sub.Ticket.ExpireAt.Format("2006-01-02 15:04 MST")),
+		Server:   "127.0.0.1",
+		Port:     1024,
+		Password: renderer.PasswordReserve,
+		Cipher:   "chacha20-ietf-poly1305",
+		Plugin:   sharing_link.SIP003{},
+	}
+	lines := []string{alert.ExportToURL()}
+	for _, link := range renderer.ToSharingLinks(sub.Nodes()) {
+		lines = append(lines, link.ExportToURL())
+	}
+	return []byte(b64.StdEncoding.EncodeToString([]byte(strings.Join(lines, "\n")))), nil
+}
@@ -0,0 +1,73 @@
+package clash
+
+import (
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/model"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/model/sharing_link"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/renderer"
+)
+
+func init() {
+	renderer.Register("clash", Clash{})
+}
+
+const (
+	groupProxy    = "Proxy"
+	groupAuto     = "Auto"
+	groupEndpoint = "Endpoints"
+	groupRelay    = "Relays"
+)
+
+// Clash renders a Clash configuration with proxy groups of endpoints, relays and url-test.
+type Clash struct{}
+
+func (Clash) ContentType() string {
+	return "text/yaml; charset=utf-8"
+}
+
+func (Clash) Render(sub *model.Subscription) ([]byte, error) {
+	var conf sharing_link.Clash
+	uniqueName := renderer.UniqueNamer()
+	toProxies := func(nodes []model.SubscriptionNode) (names []string) {
+		for _, link := range renderer.ToSharingLinks(nodes) {
+			exporter, ok := link.(sharing_link.ClashExporter)
+			if !ok {
+				continue
+			}
+			proxy := exporter.ExportToClash()
+			if proxy == nil {
+				continue
+			}
+			proxy.Name = uniqueName(proxy.Name)
+			conf.Proxies = append(conf.Proxies, proxy)
+			names = append(names, proxy.Name)
+		}
+		return names
+	}
+	endpointNames := toProxies(sub.Endpoints)
+	relayNames := toProxies(sub.Relays)
+
+	proxyGroup := sharing_link.ClashProxyGroup{Name: groupProxy, Type: "select"}
+	var groups []sharing_link.ClashProxyGroup
+	if allNames := append(append([]string{}, endpointNames...), relayNames...); len(allNames) > 0 {
+		proxyGroup.Proxies = append(proxyGroup.Proxies, groupAuto)
+		groups = append(groups, sharing_link.ClashProxyGroup{
+			Name:     groupAuto,
+			Type:     "url-test",
+			Proxies:  allNames,
+			URL:      "http://www.gstatic.com/generate_204",
+			Interval: 300,
+		})
+	}
+	if len(endpointNames) > 0 {
+		proxyGroup.Proxies = append(proxyGroup.Proxies, groupEndpoint)
+		groups = append(groups, sharing_link.ClashProxyGroup{Name: groupEndpoint, Type: "select", Proxies: endpointNames})
+	}
+	if len(relayNames) > 0 {
+		proxyGroup.Proxies = append(proxyGroup.Proxies, groupRelay)
+		groups = append(groups, sharing_link.ClashProxyGroup{Name: groupRelay, Type: "select", Proxies: relayNames})
+	}
+	proxyGroup.Proxies = append(proxyGroup.Proxies, "DIRECT")
+	conf.ProxyGroups = append([]sharing_link.ClashProxyGroup{proxyGroup}, groups...)
+	conf.Rules = []string{"MATCH," + groupProxy}
+	return []byte(conf.ExportToString()), nil
+}
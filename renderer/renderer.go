@@ -0,0 +1,147 @@
+package renderer
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/daeuniverse/softwind/protocol"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/common"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/model"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/model/sharing_link"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/pkg/log"
+)
+
+const PasswordReserve = "__SWEETLISA__"
+
+// Renderer renders the subscription into a format of clients.
+type Renderer interface {
+	ContentType() string
+	Render(sub *model.Subscription) ([]byte, error)
+}
+
+var Mapper = make(map[string]Renderer)
+
+func Register(name string, r Renderer) {
+	Mapper[name] = r
+}
+
+func NewRenderer(name string) (Renderer, error) {
+	r, ok := Mapper[name]
+	if !ok {
+		return nil, fmt.Errorf("no renderer registered for %v", strconv.Quote(name))
+	}
+	return r, nil
+}
+
+// ToSharingLink converts the node to the sharing link of its protocol.
+// It returns nil if the protocol is unexpected.
+func ToSharingLink(node *model.SubscriptionNode) sharing_link.URLExporter {
+	arg := node.Argument
+	switch arg.Protocol {
+	case protocol.ProtocolShadowsocks:
+		return &sharing_link.SIP002{
+			Name:     node.Name,
+			Server:   node.Host,
+			Port:     node.Port,
+			Password: arg.Password,
+			Cipher:   arg.Method,
+			Plugin:   sharing_link.SIP003{},
+		}
+	case protocol.ProtocolVMessTCP:
+		return &sharing_link.V2RayN{
+			Ps:   node.Name,
+			Add:  node.Host,
+			Port: strconv.Itoa(node.Port),
+			ID:   arg.Password,
+			Aid:  "0",
+			Net:  "tcp",
+			Type: "none",
+			V:    "2",
+		}
+	case protocol.ProtocolVMessTlsGrpc:
+		return &sharing_link.V2RayN{
+			Ps:   node.Name,
+			Add:  node.Host,
+			Port: strconv.Itoa(node.Port),
+			ID:   arg.Password,
+			Aid:  "0",
+			Net:  "grpc",
+			TLS:  "tls",
+			Type: "none",
+			Sni:  node.Sni,
+			Host: node.Sni,
+			Path: common.SimplyGetParam(arg.Method, "serviceName"),
+			V:    "2",
+		}
+	case protocol.ProtocolJuicity:
+		return &sharing_link.Juicity{
+			Name:                  node.Name,
+			Server:                node.Host,
+			Port:                  node.Port,
+			User:                  arg.Username,
+			Password:              arg.Password,
+			Sni:                   node.Sni,
+			AllowInsecure:         false,
+			CongestionControl:     "bbr",
+			PinnedCertchainSha256: common.SimplyGetParam(arg.Method, "pinned_certchain_sha256"),
+			Protocol:              "juicity",
+		}
+	case model.ProtocolTrojan:
+		return &sharing_link.Trojan{
+			Name:     node.Name,
+			Server:   node.Host,
+			Port:     node.Port,
+			Password: arg.Password,
+			Sni:      node.Sni,
+		}
+	case model.ProtocolVLESSReality:
+		return &sharing_link.VLESSReality{
+			Name:        node.Name,
+			Server:      node.Host,
+			Port:        node.Port,
+			ID:          arg.Password,
+			Sni:         node.Sni,
+			Fingerprint: common.SimplyGetParam(arg.Method, "fp"),
+			PublicKey:   arg.PublicKey,
+			ShortId:     arg.FirstShortId(),
+		}
+	case model.ProtocolHysteria2:
+		return &sharing_link.Hysteria2{
+			Name:         node.Name,
+			Server:       node.Host,
+			Port:         node.Port,
+			Password:     arg.Password,
+			Sni:          node.Sni,
+			Obfs:         common.SimplyGetParam(arg.Method, "obfs"),
+			ObfsPassword: common.SimplyGetParam(arg.Method, "obfs-password"),
+			PinSHA256:    common.SimplyGetParam(arg.Method, "pinSHA256"),
+			UpMbps:       node.UpMbps,
+			DownMbps:     node.DownMbps,
+		}
+	default:
+		log.Warn("unexpected protocol: %v", arg.Protocol)
+		return nil
+	}
+}
+
+// ToSharingLinks converts the nodes to sharing links and skips the unexpected ones.
+func ToSharingLinks(nodes []model.SubscriptionNode) (links []sharing_link.URLExporter) {
+	for i := range nodes {
+		if link := ToSharingLink(&nodes[i]); link != nil {
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+// UniqueNamer returns a function that appends a sequence number to the duplicate names,
+// because nodes of servers with multiple hosts share the same name.
+func UniqueNamer() func(name string) string {
+	nameCnt := make(map[string]int)
+	return func(name string) string {
+		if nameCnt[name]++; nameCnt[name] > 1 {
+			return fmt.Sprintf("%v (%v)", name, nameCnt[name])
+		}
+		return name
+	}
+}
@@ -0,0 +1,65 @@
+package singbox
+
+import (
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/model"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/model/sharing_link"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/renderer"
+)
+
+func init() {
+	renderer.Register("singbox", SingBox{})
+	renderer.Register("sing-box", SingBox{})
+}
+
+const (
+	tagProxy  = "proxy"
+	tagAuto   = "auto"
+	tagDirect = "direct"
+)
+
+// SingBox renders sing-box outbounds with a selector and a urltest outbound.
+type SingBox struct{}
+
+func (SingBox) ContentType() string {
+	return "application/json; charset=utf-8"
+}
+
+func (SingBox) Render(sub *model.Subscription) ([]byte, error) {
+	var conf sharing_link.SingBox
+	uniqueName := renderer.UniqueNamer()
+	var tags []string
+	for _, link := range renderer.ToSharingLinks(sub.Nodes()) {
+		exporter, ok := link.(sharing_link.SingBoxExporter)
+		if !ok {
+			continue
+		}
+		outbound := exporter.ExportToSingBox()
+		if outbound == nil {
+			continue
+		}
+		outbound.Tag = uniqueName(outbound.Tag)
+		conf.Outbounds = append(conf.Outbounds, outbound)
+		tags = append(tags, outbound.Tag)
+	}
+
+	selector := &sharing_link.SingBoxOutbound{
+		Type:      "selector",
+		Tag:       tagProxy,
+		Outbounds: []string{tagDirect},
+		Default:   tagDirect,
+	}
+	if len(tags) > 0 {
+		selector.Outbounds = append([]string{tagAuto}, append(tags, tagDirect)...)
+		selector.Default = tagAuto
+		conf.Outbounds = append([]*sharing_link.SingBoxOutbound{{
+			Type:      "urltest",
+			Tag:       tagAuto,
+			Outbounds: tags,
+			URL:       "https://www.gstatic.com/generate_204",
+			Interval:  "5m",
+		}}, conf.Outbounds...)
+	}
+	conf.Outbounds = append([]*sharing_link.SingBoxOutbound{selector}, conf.Outbounds...)
+	conf.Outbounds = append(conf.Outbounds, &sharing_link.SingBoxOutbound{Type: "direct", Tag: tagDirect})
+	return []byte(conf.ExportToString()), nil
+}
@@ -0,0 +1,52 @@
+package sip008
+
+import (
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/model"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/model/sharing_link"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/renderer"
+)
+
+func init() {
+	renderer.Register("sip008", SIP008{})
+}
+
+// SIP008 renders a SIP008 document of shadowsocks nodes, and the byte counters are
+// summed up from the bandwidth limits of servers.
+type SIP008 struct{}
+
+func (SIP008) ContentType() string {
+	return "application/json; charset=utf-8"
+}
+
+func (SIP008) Render(sub *model.Subscription) ([]byte, error) {
+	conf := sharing_link.SIP008{
+		Version: 1,
+		Servers: []sharing_link.SIP008Server{},
+	}
+	for _, link := range renderer.ToSharingLinks(sub.Nodes()) {
+		exporter, ok := link.(sharing_link.SIP008Exporter)
+		if !ok {
+			continue
+		}
+		if server := exporter.ExportToSIP008Server(); server != nil {
+			conf.Servers = append(conf.Servers, *server)
+		}
+	}
+	var used, remaining int64
+	var limited bool
+	for _, server := range sub.Servers {
+		uplink, downlink := server.BandwidthLimit.UsedKiB()
+		used += uplink + downlink
+		if r, ok := server.BandwidthLimit.RemainingKiB(); ok {
+			remaining += r
+			limited = true
+		}
+	}
+	used *= 1024
+	conf.BytesUsed = &used
+	if limited {
+		remaining *= 1024
+		conf.BytesRemaining = &remaining
+	}
+	return []byte(conf.ExportToString()), nil
+}
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/common"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/config"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/db"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/manager"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/model"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/pkg/acme"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/pkg/log"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/pkg/nameserver"
+	jsoniter "github.com/json-iterator/go"
+)
+
+const acmeAccountKey = "accountKey"
+
+var (
+	// acmeLocks serializes the issuance of every domain to avoid duplicate orders of the same domain.
+	acmeLocks   = make(map[string]chan struct{})
+	acmeLocksMu sync.Mutex
+)
+
+// lockDomain waits for the issuance lock of the domain until ctx is done. unlock must be called if err is nil.
+func lockDomain(ctx context.Context, domain string) (unlock func(), err error) {
+	acmeLocksMu.Lock()
+	l, ok := acmeLocks[domain]
+	if !ok {
+		l = make(chan struct{}, 1)
+		acmeLocks[domain] = l
+	}
+	acmeLocksMu.Unlock()
+	select {
+	case l <- struct{}{}:
+		return func() { <-l }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// ACMEEnabled reports if SweetLisa issues the certificates for the subdomains of TLS servers.
+func ACMEEnabled() bool {
+	conf := config.GetConfig()
+	return conf.ACMEDirectory != "" && conf.NameserverName != "" && conf.NameserverToken != ""
+}
+
+// ServerCertificateDomain returns the subdomain assigned to the server. ok is false if the server
+// does not need a certificate issued by SweetLisa.
+func ServerCertificateDomain(server model.Server) (domain string, ok bool) {
+	if !server.Argument.Protocol.WithTLS() {
+		return "", false
+	}
+	host := model.GetFirstHost(server.Hosts)
+	if _, err := netip.ParseAddr(host); err != nil {
+		// the domain is not assigned by SweetLisa
+		return "", false
+	}
+	domain, err := common.HostToSNI(host, config.GetConfig().Host)
+	if err != nil {
+		return "", false
+	}
+	return domain, true
+}
+
+func getACMEAccountKey() (key *ecdsa.PrivateKey, err error) {
+	err = db.DB().Update(func(tx *bolt.Tx) error {
+		bkt, err := tx.CreateBucketIfNotExists([]byte(model.BucketACME))
+		if err != nil {
+			return err
+		}
+		if b := bkt.Get([]byte(acmeAccountKey)); b != nil {
+			key, err = acme.ParsePrivateKey(b)
+			return err
+		}
+		if key, err = acme.GenerateKey(); err != nil {
+			return err
+		}
+		b, err := acme.MarshalPrivateKey(key)
+		if err != nil {
+			return err
+		}
+		return bkt.Put([]byte(acmeAccountKey), b)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("getACMEAccountKey: %w", err)
+	}
+	return key, nil
+}
+
+func GetCertificate(tx *bolt.Tx, domain string) (cert *model.Certificate, err error) {
+	f := func(tx *bolt.Tx) error {
+		bkt := tx.Bucket([]byte(model.BucketCertificate))
+		if bkt == nil {
+			return db.ErrKeyNotFound
+		}
+		b := bkt.Get([]byte(domain))
+		if b == nil {
+			return db.ErrKeyNotFound
+		}
+		var c model.Certificate
+		if err := jsoniter.Unmarshal(b, &c); err != nil {
+			return err
+		}
+		cert = &c
+		return nil
+	}
+	if tx != nil {
+		err = f(tx)
+	} else {
+		err = db.DB().View(f)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GetCertificate: %w", err)
+	}
+	return cert, nil
+}
+
+func SaveCertificate(wtx *bolt.Tx, cert model.Certificate) (err error) {
+	f := func(tx *bolt.Tx) error {
+		bkt, err := tx.CreateBucketIfNotExists([]byte(model.BucketCertificate))
+		if err != nil {
+			return err
+		}
+		b, err := jsoniter.Marshal(cert)
+		if err != nil {
+			return err
+		}
+		return bkt.Put([]byte(cert.Domain), b)
+	}
+	if wtx != nil {
+		err = f(wtx)
+	} else {
+		err = db.DB().Update(f)
+	}
+	if err != nil {
+		return fmt.Errorf("SaveCertificate: %w", err)
+	}
+	return nil
+}
+
+// ObtainCertificate issues a certificate of the domain by the ACME DNS-01 challenge.
+func ObtainCertificate(ctx context.Context, domain string) (cert *model.Certificate, err error) {
+	conf := config.GetConfig()
+	ns, err := nameserver.NewNameserver(conf.NameserverName, conf.NameserverToken)
+	if err != nil {
+		return nil, fmt.Errorf("ObtainCertificate: %w", err)
+	}
+	key, err := getACMEAccountKey()
+	if err != nil {
+		return nil, fmt.Errorf("ObtainCertificate: %w", err)
+	}
+	client := acme.NewClient(conf.ACMEDirectory, key, ns)
+	if err = client.Register(ctx, conf.ACMEEmail); err != nil {
+		return nil, fmt.Errorf("ObtainCertificate: Register: %w", err)
+	}
+	c, err := client.Obtain(ctx, domain)
+	if err != nil {
+		return nil, fmt.Errorf("ObtainCertificate: %v: %w", domain, err)
+	}
+	return &model.Certificate{
+		Domain:         domain,
+		CertificatePEM: string(c.CertificatePEM),
+		PrivateKeyPEM:  string(c.PrivateKeyPEM),
+		NotAfter:       c.NotAfter,
+	}, nil
+}
+
+// DeliverCertificate sends the certificate to the server over the management channel.
+func DeliverCertificate(ctx context.Context, server model.Server, cert model.Certificate) error {
+	mng, err := manager.NewManager(ChooseDialer(server), manager.ManageArgument{
+		Host:       model.GetFirstHost(server.Hosts),
+		Port:       strconv.Itoa(server.Port),
+		RootDomain: config.GetConfig().Host,
+		Argument:   server.Argument,
+	})
+	if err != nil {
+		return fmt.Errorf("DeliverCertificate: NewManager(%v): %w", server.Name, err)
+	}
+	if err = mng.DeliverCertificate(ctx, cert); err != nil {
+		return fmt.Errorf("DeliverCertificate: %v: %w", server.Name, err)
+	}
+	return nil
+}
+
+// certificateAction decides if the certificate should be issued and if it should be delivered to the server.
+// cert is nil if there is no certificate of the domain.
+func certificateAction(cert *model.Certificate, now time.Time, forceDeliver bool) (issue bool, deliver bool) {
+	issue = cert == nil || cert.NeedRenew(now)
+	return issue, issue || forceDeliver
+}
+
+// ServeCertificate issues or renews the certificate of the subdomain of the server if necessary, and delivers
+// it to the server if it is renewed or forceDeliver is true.
+func ServeCertificate(ctx context.Context, server model.Server, forceDeliver bool) (err error) {
+	domain, ok := ServerCertificateDomain(server)
+	if !ok {
+		return nil
+	}
+	unlock, err := lockDomain(ctx, domain)
+	if err != nil {
+		return fmt.Errorf("ServeCertificate: %v: %w", domain, err)
+	}
+	cert, err := GetCertificate(nil, domain)
+	if err != nil {
+		cert = nil
+	}
+	// the error of GetCertificate is overwritten by the issuance
+	issue, deliver := certificateAction(cert, time.Now(), forceDeliver)
+	if issue {
+		log.Info("issue the certificate of %v for %v", domain, server.Name)
+		if cert, err = ObtainCertificate(ctx, domain); err == nil {
+			err = SaveCertificate(nil, *cert)
+		}
+	}
+	unlock()
+	if err != nil {
+		return fmt.Errorf("ServeCertificate: %w", err)
+	}
+	if !deliver {
+		return nil
+	}
+	return DeliverCertificate(ctx, server, *cert)
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/model"
+)
+
+func TestCertificateAction(t *testing.T) {
+	now := time.Now()
+	valid := &model.Certificate{NotAfter: now.Add(60 * 24 * time.Hour)}
+	expiring := &model.Certificate{NotAfter: now.Add(24 * time.Hour)}
+	for _, c := range []struct {
+		name         string
+		cert         *model.Certificate
+		forceDeliver bool
+		issue        bool
+		deliver      bool
+	}{
+		{"no certificate", nil, false, true, true},
+		{"no certificate and force", nil, true, true, true},
+		{"valid", valid, false, false, false},
+		{"valid and force", valid, true, false, true},
+		{"expiring", expiring, false, true, true},
+		{"expiring and force", expiring, true, true, true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			issue, deliver := certificateAction(c.cert, now, c.forceDeliver)
+			if issue != c.issue || deliver != c.deliver {
+				t.Fatalf("got (issue %v, deliver %v), want (issue %v, deliver %v)", issue, deliver, c.issue, c.deliver)
+			}
+		})
+	}
+}
+
+func TestLockDomain(t *testing.T) {
+	unlock, err := lockDomain(context.Background(), "a.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// other domains are not blocked
+	unlockB, err := lockDomain(context.Background(), "b.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlockB()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err = lockDomain(ctx, "a.example.com"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the deadline exceeded, got %v", err)
+	}
+
+	unlock()
+	unlock, err = lockDomain(context.Background(), "a.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/config"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/model"
+)
+
+// RelayChain is the relays in order that users connect to. The last relay connects to the endpoint server.
+type RelayChain []model.Server
+
+func (c RelayChain) Tickets() []string {
+	tickets := make([]string, 0, len(c))
+	for _, relay := range c {
+		tickets = append(tickets, relay.Ticket)
+	}
+	return tickets
+}
+
+func (c RelayChain) Contains(ticket string) bool {
+	for _, relay := range c {
+		if relay.Ticket == ticket {
+			return true
+		}
+	}
+	return false
+}
+
+func (c RelayChain) String() string {
+	names := make([]string, 0, len(c))
+	for _, relay := range c {
+		names = append(names, relay.Name)
+	}
+	return strings.Join(names, " -> ")
+}
+
+// CanReach reports if every relay of the chain can forward to the server according to the relay rules.
+func (c RelayChain) CanReach(server *model.Server) bool {
+	for i := range c {
+		if !model.CanRelay(&c[i], server) {
+			return false
+		}
+	}
+	return true
+}
+
+// MaxRelayHops returns the maximum number of relays in a chain.
+func MaxRelayHops() int {
+	if n := config.GetConfig().MaxRelayHops; n > 1 {
+		return n
+	}
+	return 1
+}
+
+// GetRelayChainsFrom returns all chains starting with the first relay and followed by distinct other relays.
+// The number of relays in a chain is not greater than maxHops.
+func GetRelayChainsFrom(first model.Server, others []model.Server, maxHops int) (chains []RelayChain) {
+	var dfs func(chain RelayChain)
+	dfs = func(chain RelayChain) {
+		chains = append(chains, chain)
+		if len(chain) >= maxHops {
+			return
+		}
+		for _, relay := range others {
+			if chain.Contains(relay.Ticket) {
+				continue
+			}
+			next := make(RelayChain, len(chain), len(chain)+1)
+			copy(next, chain)
+			dfs(append(next, relay))
+		}
+	}
+	dfs(RelayChain{first})
+	return chains
+}
+
+// GetRelayChains returns all chains of distinct relays, whose lengths are not greater than maxHops.
+func GetRelayChains(relays []model.Server, maxHops int) (chains []RelayChain) {
+	for _, relay := range relays {
+		chains = append(chains, GetRelayChainsFrom(relay, relays, maxHops)...)
+	}
+	return chains
+}
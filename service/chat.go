@@ -0,0 +1,61 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/boltdb/bolt"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/db"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/model"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// GetChat returns the settings of the chat. Default settings are returned if the chat has not been set.
+func GetChat(tx *bolt.Tx, chatIdentifier string) (chat model.Chat, err error) {
+	chat = model.Chat{ChatIdentifier: chatIdentifier}
+	f := func(tx *bolt.Tx) error {
+		bkt := tx.Bucket([]byte(model.BucketChat))
+		if bkt == nil {
+			return nil
+		}
+		b := bkt.Get([]byte(chatIdentifier))
+		if b == nil {
+			return nil
+		}
+		return jsoniter.Unmarshal(b, &chat)
+	}
+	if tx != nil {
+		err = f(tx)
+	} else {
+		err = db.DB().View(f)
+	}
+	if err != nil {
+		return model.Chat{}, fmt.Errorf("GetChat: %w", err)
+	}
+	return chat, nil
+}
+
+func SaveChat(wtx *bolt.Tx, chat model.Chat) (err error) {
+	if chat.ChatIdentifier == "" {
+		return fmt.Errorf("chatIdentifier cannot be empty")
+	}
+	f := func(tx *bolt.Tx) error {
+		bkt, err := tx.CreateBucketIfNotExists([]byte(model.BucketChat))
+		if err != nil {
+			return err
+		}
+		b, err := jsoniter.Marshal(chat)
+		if err != nil {
+			return err
+		}
+		return bkt.Put([]byte(chat.ChatIdentifier), b)
+	}
+	if wtx != nil {
+		err = f(wtx)
+	} else {
+		err = db.DB().Update(f)
+	}
+	if err != nil {
+		return fmt.Errorf("SaveChat: %w", err)
+	}
+	return nil
+}
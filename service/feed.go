@@ -25,6 +25,14 @@ const (
 	ServerActionBandwidthExhausted              = "🈳 Bandwidth Exhausted"
 	ServerActionBandwidthReset                  = "🈵 Bandwidth Reset"
 	ServerActionServerInfoChanged               = "🎲 Server Info Changed"
+	ServerActionDNSRemovalFailed                = "🚧 DNS Records Removal Failed"
+)
+
+type TicketAction string
+
+const (
+	TicketActionQuotaExceeded TicketAction = "🈲 Quota Exceeded"
+	TicketActionQuotaRestored              = "🈶 Quota Restored"
 )
 
 type FeedFormat int
@@ -163,6 +171,46 @@ func AddFeedServer(wtx *bolt.Tx, server model.Server, action ServerAction) (err
 	default:
 		title = fmt.Sprintf("%v (%v): %v [%v]", action, typ, server.Name, server.Hosts)
 	}
+	if err = AddFeed(wtx, tic.ChatIdentifier, feeds.Item{
+		Title: title,
+		Link: &feeds.Link{
+			Href: u.String(),
+		},
+		Created: time.Now(),
+	}); err != nil {
+		return err
+	}
+	notifyServer(wtx, tic.ChatIdentifier, server, action, title)
+	return nil
+}
+
+// AddFeedSubDomain adds a feed about the subdomain, whose server may have been removed.
+func AddFeedSubDomain(wtx *bolt.Tx, sub model.SubDomain, action ServerAction) (err error) {
+	u := url.URL{
+		Scheme: "https",
+		Host:   config.GetConfig().Host,
+		Path:   path.Join("chat", sub.ChatIdentifier),
+	}
+	title := fmt.Sprintf("%v: %v [%v]", action, sub.ServerName, sub.Domain)
+	if sub.LastError != "" {
+		title += ": " + sub.LastError
+	}
+	return AddFeed(wtx, sub.ChatIdentifier, feeds.Item{
+		Title: title,
+		Link: &feeds.Link{
+			Href: u.String(),
+		},
+		Created: time.Now(),
+	})
+}
+
+func AddFeedTicket(wtx *bolt.Tx, tic model.Ticket, usage model.TicketUsage, action TicketAction) (err error) {
+	u := url.URL{
+		Scheme: "https",
+		Host:   config.GetConfig().Host,
+		Path:   path.Join("chat", tic.ChatIdentifier),
+	}
+	title := fmt.Sprintf("%v (User): %v [%.1f/%vGB]", action, tic.Masked(), float64(usage.TotalKiB())/1000/1000, tic.QuotaGiB)
 	return AddFeed(wtx, tic.ChatIdentifier, feeds.Item{
 		Title: title,
 		Link: &feeds.Link{
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"time"
+
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/model"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/pkg/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricsNamespace = "sweetlisa"
+
+var (
+	PingDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "ping_duration_seconds",
+		Help:      "Latency of the succeeded pings to servers and relays.",
+		Buckets:   []float64{.05, .1, .2, .3, .5, .75, 1, 2, 5, 10, 30},
+	}, []string{"chat", "name", "type"})
+	PingFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "ping_failures_total",
+		Help:      "Number of the failed pings to servers and relays.",
+	}, []string{"chat", "name", "type"})
+	SyncResults = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "sync_total",
+		Help:      "Number of passage syncs to servers and relays by result.",
+	}, []string{"chat", "name", "type", "result"})
+)
+
+var (
+	serverFailureCountDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "server", "failure_count"),
+		"Number of consecutive failed pings of the server.",
+		[]string{"chat", "name", "type"}, nil,
+	)
+	serverLastSeenDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "server", "last_seen_age_seconds"),
+		"Seconds since the last succeeded ping of the server.",
+		[]string{"chat", "name", "type"}, nil,
+	)
+	serverBandwidthRemainingDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "server", "bandwidth_remaining_bytes"),
+		"Remaining bandwidth of the server in the current cycle. Servers without limit are not exported.",
+		[]string{"chat", "name", "type"}, nil,
+	)
+	serverSyncPendingDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "server", "sync_pending"),
+		"Whether the server should be synced next seen.",
+		[]string{"chat", "name", "type"}, nil,
+	)
+)
+
+func init() {
+	prometheus.MustRegister(PingDuration, PingFailures, SyncResults, serverCollector{})
+}
+
+func TicketTypeLabel(typ model.TicketType) string {
+	switch typ {
+	case model.TicketTypeServer:
+		return "endpoint"
+	case model.TicketTypeRelay:
+		return "relay"
+	case model.TicketTypeUser:
+		return "user"
+	default:
+		return "invalid"
+	}
+}
+
+// ObservePing records the result of a ping to the server.
+func ObservePing(server model.Server, duration time.Duration, err error) {
+	tic, e := GetValidTicketObj(nil, server.Ticket)
+	if e != nil {
+		return
+	}
+	labels := prometheus.Labels{"chat": tic.ChatIdentifier, "name": server.Name, "type": TicketTypeLabel(tic.Type)}
+	if err != nil {
+		PingFailures.With(labels).Inc()
+		return
+	}
+	PingDuration.With(labels).Observe(duration.Seconds())
+}
+
+// ObserveSync records the result of a passage sync to the server.
+func ObserveSync(server model.Server, result string) {
+	tic, e := GetValidTicketObj(nil, server.Ticket)
+	if e != nil {
+		return
+	}
+	SyncResults.WithLabelValues(tic.ChatIdentifier, server.Name, TicketTypeLabel(tic.Type), result).Inc()
+}
+
+// serverCollector collects the status of servers and relays from the database at every scrape.
+type serverCollector struct{}
+
+func (serverCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- serverFailureCountDesc
+	ch <- serverLastSeenDesc
+	ch <- serverBandwidthRemainingDesc
+	ch <- serverSyncPendingDesc
+}
+
+func (serverCollector) Collect(ch chan<- prometheus.Metric) {
+	chats := make(map[string]struct{})
+	for _, tic := range GetValidTickets(nil) {
+		chats[tic.ChatIdentifier] = struct{}{}
+	}
+	for chat := range chats {
+		servers, err := GetServersByChatIdentifier(nil, chat, true)
+		if err != nil {
+			log.Warn("serverCollector: %v", err)
+			continue
+		}
+		for _, server := range servers {
+			tic, err := GetValidTicketObj(nil, server.Ticket)
+			if err != nil {
+				continue
+			}
+			labels := []string{chat, server.Name, TicketTypeLabel(tic.Type)}
+			ch <- prometheus.MustNewConstMetric(serverFailureCountDesc, prometheus.GaugeValue, float64(server.FailureCount), labels...)
+			if !server.LastSeen.IsZero() {
+				ch <- prometheus.MustNewConstMetric(serverLastSeenDesc, prometheus.GaugeValue, time.Since(server.LastSeen).Seconds(), labels...)
+			}
+			if remaining, limited := server.BandwidthLimit.RemainingKiB(); limited {
+				ch <- prometheus.MustNewConstMetric(serverBandwidthRemainingDesc, prometheus.GaugeValue, float64(remaining*1024), labels...)
+			}
+			var syncPending float64
+			if server.SyncNextSeen {
+				syncPending = 1
+			}
+			ch <- prometheus.MustNewConstMetric(serverSyncPendingDesc, prometheus.GaugeValue, syncPending, labels...)
+		}
+	}
+}
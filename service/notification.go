@@ -0,0 +1,173 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/model"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/pkg/log"
+)
+
+// maxNotificationLength is a bit less than the limit of the length of telegram messages.
+const maxNotificationLength = 4000
+
+// Notifier posts the text to the telegram chat.
+type Notifier func(chatID int64, text string) error
+
+var (
+	notifier      Notifier
+	notifyMutex   sync.Mutex
+	notifyPending = make(map[int64]*pendingNotification)
+)
+
+// pendingNotification is the events of a chat waiting to be posted.
+type pendingNotification struct {
+	lastSent time.Time
+	timer    *time.Timer
+	// items are in the order of their first events
+	items []*notificationItem
+}
+
+// notificationItem is the events of a server to be coalesced into one line.
+type notificationItem struct {
+	ticket  string
+	actions []ServerAction
+	// title is the title of the latest event
+	title string
+}
+
+func (item *notificationItem) String() string {
+	if len(item.actions) == 1 {
+		return item.title
+	}
+	var order []ServerAction
+	counts := make(map[ServerAction]int)
+	for _, action := range item.actions {
+		if counts[action] == 0 {
+			order = append(order, action)
+		}
+		counts[action]++
+	}
+	summary := make([]string, 0, len(order))
+	for _, action := range order {
+		summary = append(summary, fmt.Sprintf("%v ×%v", action, counts[action]))
+	}
+	return fmt.Sprintf("%v (flapping: %v)", item.title, strings.Join(summary, ", "))
+}
+
+// SetNotifier sets the notifier to post the events of servers and relays to the chats which opt in.
+// The events are only written into the feeds if it is not set.
+func SetNotifier(n Notifier) {
+	notifyMutex.Lock()
+	defer notifyMutex.Unlock()
+	notifier = n
+}
+
+// ServerActionEvent returns the notification event of the action. ok is false if the action is never posted.
+func ServerActionEvent(action ServerAction) (event model.NotificationEvent, ok bool) {
+	switch action {
+	case ServerActionLaunch:
+		return model.NotificationEventLaunch, true
+	case ServerActionDisconnect:
+		return model.NotificationEventDisconnect, true
+	case ServerActionReconnect:
+		return model.NotificationEventReconnect, true
+	case ServerActionBandwidthExhausted:
+		return model.NotificationEventExhausted, true
+	case ServerActionBandwidthReset:
+		return model.NotificationEventReset, true
+	case ServerActionServerInfoChanged:
+		return model.NotificationEventChanged, true
+	default:
+		return "", false
+	}
+}
+
+// notifyServer queues the event of the server to post to the chat if the chat opts in and does not mute it.
+// Events in a chat are posted at most once per model.NotificationInterval, and those of the same server are
+// coalesced into one line. If tx is not nil, the event is queued after tx is committed.
+func notifyServer(tx *bolt.Tx, chatIdentifier string, server model.Server, action ServerAction, title string) {
+	event, ok := ServerActionEvent(action)
+	if !ok {
+		return
+	}
+	chat, err := GetChat(tx, chatIdentifier)
+	if err != nil {
+		log.Warn("notifyServer: %v", err)
+		return
+	}
+	if !chat.Notify || chat.TelegramChatID == 0 || chat.Muted(event) {
+		return
+	}
+	if tx != nil {
+		// the event should not be posted if tx is rolled back
+		tx.OnCommit(func() {
+			queueNotification(chat.TelegramChatID, server, action, title)
+		})
+		return
+	}
+	queueNotification(chat.TelegramChatID, server, action, title)
+}
+
+func queueNotification(chatID int64, server model.Server, action ServerAction, title string) {
+	notifyMutex.Lock()
+	defer notifyMutex.Unlock()
+	if notifier == nil {
+		return
+	}
+	p, ok := notifyPending[chatID]
+	if !ok {
+		p = &pendingNotification{}
+		notifyPending[chatID] = p
+	}
+	var item *notificationItem
+	for _, it := range p.items {
+		if it.ticket == server.Ticket {
+			item = it
+			break
+		}
+	}
+	if item == nil {
+		item = &notificationItem{ticket: server.Ticket}
+		p.items = append(p.items, item)
+	}
+	item.actions = append(item.actions, action)
+	item.title = title
+	if p.timer == nil {
+		delay := model.NotificationDelay
+		if wait := time.Until(p.lastSent.Add(model.NotificationInterval)); wait > delay {
+			delay = wait
+		}
+		p.timer = time.AfterFunc(delay, func() {
+			flushNotification(chatID)
+		})
+	}
+}
+
+func flushNotification(chatID int64) {
+	notifyMutex.Lock()
+	p := notifyPending[chatID]
+	items := p.items
+	p.items = nil
+	p.timer = nil
+	p.lastSent = time.Now()
+	n := notifier
+	notifyMutex.Unlock()
+	if n == nil || len(items) == 0 {
+		return
+	}
+	lines := make([]string, 0, len(items))
+	for _, item := range items {
+		lines = append(lines, item.String())
+	}
+	text := strings.Join(lines, "\n")
+	if len(text) > maxNotificationLength {
+		text = strings.ToValidUTF8(text[:maxNotificationLength], "") + "\n..."
+	}
+	if err := n(chatID, text); err != nil {
+		log.Warn("flushNotification: chat %v: %v", chatID, err)
+	}
+}
@@ -52,6 +52,11 @@ func GetPassagesByServer(tx *bolt.Tx, serverTicket string) (passages []model.Pas
 		}
 
 		chatIdentifier := serverTicketObj.ChatIdentifier
+		maxRelayHops := MaxRelayHops()
+		chat, err := GetChat(tx, chatIdentifier)
+		if err != nil {
+			return err
+		}
 
 		// generate all user/relay passages in this chat
 		var userTickets []string
@@ -73,6 +78,10 @@ func GetPassagesByServer(tx *bolt.Tx, serverTicket string) (passages []model.Pas
 			switch ticket.Type {
 			case model.TicketTypeUser:
 				// user ticket
+				if IsTicketQuotaExceeded(tx, ticket) {
+					// do not generate Passages for users exceeding the quota
+					return nil
+				}
 				userTickets = append(userTickets, ticket.Ticket)
 			case model.TicketTypeServer:
 				// server ticket
@@ -89,8 +98,9 @@ func GetPassagesByServer(tx *bolt.Tx, serverTicket string) (passages []model.Pas
 				}
 			case model.TicketTypeRelay:
 				// relay ticket
-				// only the server need relays
-				if serverTicketObj.Type == model.TicketTypeServer {
+				// the server need relays, and the relay need other relays to build chains
+				if serverTicketObj.Type == model.TicketTypeServer ||
+					ticket.Ticket != serverTicket && maxRelayHops > 1 {
 					relay, err := GetServerByTicket(tx, ticket.Ticket)
 					if err != nil {
 						if !errors.Is(err, db.ErrKeyNotFound) {
@@ -117,6 +127,9 @@ func GetPassagesByServer(tx *bolt.Tx, serverTicket string) (passages []model.Pas
 						log.Info("Skip relay %v due to [notAlive:%v exhausted:%v]", relay.Name, notAlive, exhausted)
 						continue
 					}
+					if !model.CanRelay(&relay, &serverObj) {
+						continue
+					}
 					passages = append(passages, model.Passage{
 						In: model.In{
 							From:     relay.Name,
@@ -126,8 +139,16 @@ func GetPassagesByServer(tx *bolt.Tx, serverTicket string) (passages []model.Pas
 				}
 			}
 		case model.TicketTypeRelay:
-			// relay inbounds are for users but related with servers (n*m)
-			// relay outbounds are for servers
+			// relay inbounds are for users and upstream relays but related with servers and chains
+			// relay outbounds are for servers or downstream relays
+			var availableRelays []model.Server
+			for _, relay := range relays {
+				if relay.FailureCount >= model.MaxFailureCount || relay.BandwidthLimit.Exhausted() {
+					continue
+				}
+				availableRelays = append(availableRelays, relay)
+			}
+			chains := GetRelayChainsFrom(serverObj, availableRelays, maxRelayHops)
 			for _, svr := range servers {
 				if svr.NoRelay {
 					continue
@@ -135,19 +156,52 @@ func GetPassagesByServer(tx *bolt.Tx, serverTicket string) (passages []model.Pas
 				if svr.FailureCount >= model.MaxFailureCount || svr.BandwidthLimit.Exhausted() {
 					continue
 				}
-				argRelayServer := model.GetUserArgument(svr.Ticket, serverTicket, svr.Argument)
-				for _, userTicket := range userTickets {
-					argUserRelayServer := model.GetRelayUserArgument(svr.Ticket, serverTicket, userTicket, serverObj.Argument)
-					passages = append(passages, model.Passage{
-						In: model.In{Argument: argUserRelayServer},
-						Out: &model.Out{
-							To: svr.Name,
-							// FIXME: Relay only connects to the first host of the endpoint server
-							Host:     model.GetFirstHost(svr.Hosts),
-							Port:     strconv.Itoa(svr.Port),
-							Argument: argRelayServer,
-						},
-					})
+				for _, chain := range chains {
+					if !chain.CanReach(&svr) {
+						continue
+					}
+					out := &model.Out{
+						To:         svr.Name,
+						Host:       model.GetFirstHost(svr.Hosts),
+						Port:       strconv.Itoa(svr.Port),
+						Hosts:      model.GetHosts(svr.Hosts),
+						HostPolicy: chat.GetRelayHostPolicy(),
+						Argument:   model.GetUserArgument(svr.Ticket, serverTicket, svr.Argument),
+					}
+					if len(chain) > 1 {
+						next := chain[1]
+						out = &model.Out{
+							To:         next.Name,
+							Host:       model.GetFirstHost(next.Hosts),
+							Port:       strconv.Itoa(next.Port),
+							Hosts:      model.GetHosts(next.Hosts),
+							HostPolicy: chat.GetRelayHostPolicy(),
+							Argument:   model.GetChainUserArgument(svr.Ticket, chain[1:].Tickets(), serverTicket, next.Argument),
+						}
+					}
+					relayTickets := chain.Tickets()
+					for _, userTicket := range userTickets {
+						passages = append(passages, model.Passage{
+							In:  model.In{Argument: model.GetChainUserArgument(svr.Ticket, relayTickets, userTicket, serverObj.Argument)},
+							Out: out,
+						})
+					}
+					if len(chain) >= maxRelayHops {
+						continue
+					}
+					// this relay is in the middle of longer chains
+					for _, upstream := range availableRelays {
+						if chain.Contains(upstream.Ticket) || !model.CanRelay(&upstream, &svr) {
+							continue
+						}
+						passages = append(passages, model.Passage{
+							In: model.In{
+								From:     upstream.Name,
+								Argument: model.GetChainUserArgument(svr.Ticket, relayTickets, upstream.Ticket, serverObj.Argument),
+							},
+							Out: out,
+						})
+					}
 				}
 			}
 		}
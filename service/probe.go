@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/config"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/db"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/manager"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/model"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/pkg/log"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// ProbeRelay asks the relay to measure the RTTs to all hosts of the servers it can forward to.
+func ProbeRelay(ctx context.Context, relay model.Server) (probe *model.RelayProbe, err error) {
+	tic, err := GetValidTicketObj(nil, relay.Ticket)
+	if err != nil {
+		return nil, fmt.Errorf("ProbeRelay: %w", err)
+	}
+	if tic.Type != model.TicketTypeRelay {
+		return nil, fmt.Errorf("ProbeRelay: %v is not a relay", relay.Name)
+	}
+	servers, err := GetServersByChatIdentifier(nil, tic.ChatIdentifier, false)
+	if err != nil {
+		return nil, fmt.Errorf("ProbeRelay: %w", err)
+	}
+	// the names are shown to the relay instead of the tickets
+	nameToTicket := make(map[string]string)
+	var targets []model.ProbeTarget
+	for i := range servers {
+		svr := &servers[i]
+		if svr.FailureCount >= model.MaxFailureCount || !model.CanRelay(&relay, svr) {
+			continue
+		}
+		nameToTicket[svr.Name] = svr.Ticket
+		for _, host := range model.GetHosts(svr.Hosts) {
+			targets = append(targets, model.ProbeTarget{
+				To:   svr.Name,
+				Host: host,
+				Port: strconv.Itoa(svr.Port),
+			})
+		}
+	}
+	probe = &model.RelayProbe{
+		Ticket:   relay.Ticket,
+		ProbedAt: time.Now(),
+		RTTs:     make(map[string]time.Duration),
+	}
+	if len(targets) == 0 {
+		return probe, nil
+	}
+	mng, err := manager.NewManager(ChooseDialer(relay), manager.ManageArgument{
+		Host:       model.GetFirstHost(relay.Hosts),
+		Port:       strconv.Itoa(relay.Port),
+		RootDomain: config.GetConfig().Host,
+		Argument:   relay.Argument,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ProbeRelay: NewManager(%v): %w", relay.Name, err)
+	}
+	results, err := mng.ProbeUpstreams(ctx, targets)
+	if err != nil {
+		return nil, fmt.Errorf("ProbeRelay: %v: %w", relay.Name, err)
+	}
+	for _, r := range results {
+		ticket, ok := nameToTicket[r.To]
+		if !ok || r.RTT <= 0 {
+			continue
+		}
+		if rtt, ok := probe.RTTs[ticket]; !ok || r.RTT < rtt {
+			probe.RTTs[ticket] = r.RTT
+		}
+	}
+	return probe, nil
+}
+
+func SaveRelayProbe(wtx *bolt.Tx, probe model.RelayProbe) (err error) {
+	f := func(tx *bolt.Tx) error {
+		bkt, err := tx.CreateBucketIfNotExists([]byte(model.BucketProbe))
+		if err != nil {
+			return err
+		}
+		b, err := jsoniter.Marshal(probe)
+		if err != nil {
+			return err
+		}
+		return bkt.Put([]byte(probe.Ticket), b)
+	}
+	if wtx != nil {
+		err = f(wtx)
+	} else {
+		err = db.DB().Update(f)
+	}
+	if err != nil {
+		return fmt.Errorf("SaveRelayProbe: %w", err)
+	}
+	return nil
+}
+
+// GetRelayProbe returns the latest probe of the relay. It returns nil if the relay has never been probed.
+func GetRelayProbe(tx *bolt.Tx, relayTicket string) (probe *model.RelayProbe) {
+	f := func(tx *bolt.Tx) error {
+		bkt := tx.Bucket([]byte(model.BucketProbe))
+		if bkt == nil {
+			return nil
+		}
+		b := bkt.Get([]byte(relayTicket))
+		if b == nil {
+			return nil
+		}
+		var p model.RelayProbe
+		if err := jsoniter.Unmarshal(b, &p); err != nil {
+			log.Warn("GetRelayProbe: %v", err)
+			return nil
+		}
+		probe = &p
+		return nil
+	}
+	if tx != nil {
+		_ = f(tx)
+	} else {
+		_ = db.DB().View(f)
+	}
+	return probe
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/boltdb/bolt"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/db"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/model"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// GetChatRoles returns the roles of members of the chat. Empty roles are returned if no role has been set.
+func GetChatRoles(tx *bolt.Tx, chatIdentifier string) (roles model.ChatRoles, err error) {
+	roles = model.ChatRoles{ChatIdentifier: chatIdentifier}
+	f := func(tx *bolt.Tx) error {
+		bkt := tx.Bucket([]byte(model.BucketRole))
+		if bkt == nil {
+			return nil
+		}
+		b := bkt.Get([]byte(chatIdentifier))
+		if b == nil {
+			return nil
+		}
+		return jsoniter.Unmarshal(b, &roles)
+	}
+	if tx != nil {
+		err = f(tx)
+	} else {
+		err = db.DB().View(f)
+	}
+	if err != nil {
+		return model.ChatRoles{}, fmt.Errorf("GetChatRoles: %w", err)
+	}
+	return roles, nil
+}
+
+// GetRole returns the role of the member in the chat.
+func GetRole(tx *bolt.Tx, chatIdentifier string, member string) (role model.Role, err error) {
+	roles, err := GetChatRoles(tx, chatIdentifier)
+	if err != nil {
+		return model.RoleINVALID, err
+	}
+	return roles.GetRole(member), nil
+}
+
+// SetRole sets the role of the member in the chat by the operator, who must be an owner. The first owner
+// can be set by anyone identified because the roles are not enforced before that.
+func SetRole(wtx *bolt.Tx, chatIdentifier string, operator string, member string, role model.Role) (roles model.ChatRoles, err error) {
+	if member == "" {
+		return model.ChatRoles{}, fmt.Errorf("SetRole: anonymous members cannot have a role")
+	}
+	if !role.IsValid() {
+		return model.ChatRoles{}, fmt.Errorf("SetRole: unexpected role: %v", role)
+	}
+	f := func(tx *bolt.Tx) error {
+		if roles, err = GetChatRoles(tx, chatIdentifier); err != nil {
+			return err
+		}
+		if operator == "" || roles.GetRole(operator) < model.RoleOwner {
+			return model.ErrPermissionDenied
+		}
+		if roles.Roles[member] == model.RoleOwner && role != model.RoleOwner && roles.CountRole(model.RoleOwner) <= 1 {
+			return fmt.Errorf("the chat should have at least one owner")
+		}
+		if roles.Roles == nil {
+			roles.Roles = make(map[string]model.Role)
+		}
+		if role == model.RoleMember {
+			delete(roles.Roles, member)
+		} else {
+			roles.Roles[member] = role
+		}
+		bkt, err := tx.CreateBucketIfNotExists([]byte(model.BucketRole))
+		if err != nil {
+			return err
+		}
+		b, err := jsoniter.Marshal(roles)
+		if err != nil {
+			return err
+		}
+		return bkt.Put([]byte(chatIdentifier), b)
+	}
+	if wtx != nil {
+		err = f(wtx)
+	} else {
+		err = db.DB().Update(f)
+	}
+	if err != nil {
+		return model.ChatRoles{}, fmt.Errorf("SetRole: %w", err)
+	}
+	return roles, nil
+}
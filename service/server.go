@@ -85,7 +85,8 @@ func GetServersByChatIdentifier(tx *bolt.Tx, chatIdentifier string, includeRelay
 	return servers, nil
 }
 
-func AssignSubDomain(ip netip.Addr) (err error) {
+// AssignSubDomain assigns the subdomain of the ip to the server and tracks it for the removal after the server is gone.
+func AssignSubDomain(ip netip.Addr, server model.Server, chatIdentifier string) (err error) {
 	domain, _ := common.HostToSNI(ip.String(), config.GetConfig().Host)
 	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
 	defer cancel()
@@ -94,7 +95,12 @@ func AssignSubDomain(ip netip.Addr) (err error) {
 	if err != nil {
 		return err
 	}
-	return ns.Assign(ctx, domain, ip.String())
+	subDomainMutex.Lock()
+	defer subDomainMutex.Unlock()
+	if err = ns.Assign(ctx, domain, ip.String()); err != nil {
+		return err
+	}
+	return TrackSubDomain(nil, domain, server, chatIdentifier)
 }
 
 // RegisterServer save the server in db
@@ -127,21 +133,30 @@ func RegisterServer(wtx *bolt.Tx, server model.Server) (err error) {
 						if err = AddFeedServer(tx, server, ServerActionServerInfoChanged); err != nil {
 							log.Error("AddFeedServer:", err)
 						}
-						// remove old records
+						// remove old records if the server does not use them anymore. They are removed in background
+						// after the other servers sharing them are gone.
 						if old.Argument.Protocol.WithTLS() || model.GetFirstHost(old.Hosts) != model.GetFirstHost(server.Hosts) {
 							if conf := config.GetConfig(); conf.NameserverName != "" && conf.NameserverToken != "" {
-								ns, e := nameserver.NewNameserver(conf.NameserverName, conf.NameserverToken)
+								oldHost := model.GetFirstHost(old.Hosts)
+								if _, e := netip.ParseAddr(oldHost); e != nil {
+									// the domain is not assigned by SweetLisa
+									return
+								}
+								domain, e := common.HostToSNI(oldHost, conf.Host)
 								if e != nil {
-									log.Warn("RemoveRecords: %v", e)
+									log.Warn("RequestRemoveSubDomain: %v", e)
 									return
 								}
-								domain, e := common.HostToSNI(model.GetFirstHost(old.Hosts), config.GetConfig().Host)
+								if d, ok := ServerCertificateDomain(server); ok && d == domain {
+									return
+								}
+								tic, e := GetTicketObj(tx, server.Ticket)
 								if e != nil {
-									log.Warn("RemoveRecords: %v", e)
+									log.Warn("RequestRemoveSubDomain: %v", e)
 									return
 								}
-								if e = ns.RemoveRecords(context.Background(), domain); e != nil {
-									log.Warn("RemoveRecords: %v", e)
+								if e = RequestRemoveSubDomain(tx, domain, server, tic.ChatIdentifier); e != nil {
+									log.Warn("%v", e)
 								}
 							}
 						}
@@ -158,6 +173,11 @@ func RegisterServer(wtx *bolt.Tx, server model.Server) (err error) {
 		}
 		old.BandwidthLimit.Update(server.BandwidthLimit)
 		server.BandwidthLimit = old.BandwidthLimit
+		// keep the NoRelay set by the operator
+		if old.NoRelayOverridden {
+			server.NoRelay = old.NoRelay
+			server.NoRelayOverridden = true
+		}
 
 		server.FailureCount = 0
 		server.LastSeen = time.Now()
@@ -181,3 +201,132 @@ func RegisterServer(wtx *bolt.Tx, server model.Server) (err error) {
 	}
 	return nil
 }
+
+// GetServerStatuses returns the statuses of servers and relays of the chat.
+func GetServerStatuses(tx *bolt.Tx, chatIdentifier string) (statuses []model.ServerStatus, err error) {
+	f := func(tx *bolt.Tx) error {
+		servers, err := GetServersByChatIdentifier(tx, chatIdentifier, true)
+		if err != nil {
+			return err
+		}
+		ticketToName := make(map[string]string)
+		for _, svr := range servers {
+			ticketToName[svr.Ticket] = svr.Name
+		}
+		for _, svr := range servers {
+			tic, err := GetValidTicketObj(tx, svr.Ticket)
+			if err != nil {
+				continue
+			}
+			status := model.ServerStatus{
+				Ticket:       svr.Ticket,
+				Name:         svr.Name,
+				Type:         tic.Type,
+				Tags:         svr.Tags,
+				Online:       svr.FailureCount < model.MaxFailureCount,
+				NoRelay:      svr.NoRelay,
+				Exhausted:    svr.BandwidthLimit.Exhausted(),
+				FailureCount: svr.FailureCount,
+				LastSeen:     svr.LastSeen,
+				SyncNextSeen: svr.SyncNextSeen,
+			}
+			if remaining, limited := svr.BandwidthLimit.RemainingKiB(); limited {
+				status.RemainingKiB = &remaining
+			}
+			if nextResetAt, ok := svr.BandwidthLimit.NextResetAt(time.Now()); ok {
+				status.NextResetAt = &nextResetAt
+			}
+			if probe := GetRelayProbe(tx, svr.Ticket); tic.Type == model.TicketTypeRelay && probe != nil {
+				status.UpstreamRTTs = make(map[string]time.Duration)
+				for ticket, rtt := range probe.RTTs {
+					if name, ok := ticketToName[ticket]; ok {
+						status.UpstreamRTTs[name] = rtt
+					}
+				}
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	}
+	if tx != nil {
+		err = f(tx)
+	} else {
+		err = db.DB().View(f)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GetServerStatuses: %w", err)
+	}
+	return statuses, nil
+}
+
+// getChatServerTicket returns the ticket object if the ticket is a server or relay ticket of the chat.
+func getChatServerTicket(tx *bolt.Tx, ticket string, chatIdentifier string) (tic model.Ticket, err error) {
+	tic, err = GetValidTicketObj(tx, ticket)
+	if err != nil {
+		return model.Ticket{}, err
+	}
+	if tic.ChatIdentifier != chatIdentifier {
+		return model.Ticket{}, ErrInvalidTicket
+	}
+	switch tic.Type {
+	case model.TicketTypeServer, model.TicketTypeRelay:
+		return tic, nil
+	default:
+		return model.Ticket{}, ErrInvalidTicket
+	}
+}
+
+// RemoveServer revokes the server or relay ticket of the chat and removes the server record.
+func RemoveServer(wtx *bolt.Tx, ticket string, chatIdentifier string) (err error) {
+	f := func(tx *bolt.Tx) error {
+		if _, err := getChatServerTicket(tx, ticket, chatIdentifier); err != nil {
+			return err
+		}
+		return RevokeTicket(tx, ticket, chatIdentifier)
+	}
+	if wtx != nil {
+		err = f(wtx)
+	} else {
+		err = db.DB().Update(f)
+	}
+	if err != nil {
+		return fmt.Errorf("RemoveServer: %w", err)
+	}
+	return nil
+}
+
+// SetServerNoRelay sets the NoRelay of the server by the operator, which overrides the one declared at register.
+func SetServerNoRelay(wtx *bolt.Tx, ticket string, chatIdentifier string, noRelay bool) (server model.Server, err error) {
+	f := func(tx *bolt.Tx) error {
+		tic, err := getChatServerTicket(tx, ticket, chatIdentifier)
+		if err != nil {
+			return err
+		}
+		if tic.Type != model.TicketTypeServer {
+			return fmt.Errorf("only servers can be set NoRelay")
+		}
+		if server, err = GetServerByTicket(tx, ticket); err != nil {
+			return err
+		}
+		server.NoRelay = noRelay
+		server.NoRelayOverridden = true
+		bkt, err := tx.CreateBucketIfNotExists([]byte(model.BucketServer))
+		if err != nil {
+			return err
+		}
+		b, err := jsoniter.Marshal(server)
+		if err != nil {
+			return err
+		}
+		return bkt.Put([]byte(ticket), b)
+	}
+	if wtx != nil {
+		err = f(wtx)
+	} else {
+		err = db.DB().Update(f)
+	}
+	if err != nil {
+		return model.Server{}, fmt.Errorf("SetServerNoRelay: %w", err)
+	}
+	return server, nil
+}
@@ -0,0 +1,293 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/config"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/db"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/model"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/pkg/nameserver"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// ErrSubDomainInUse is returned if the subdomain waiting to be removed is still used by a valid server.
+var ErrSubDomainInUse = errors.New("the subdomain is still in use")
+
+// subDomainMutex serializes the assignment and the removal of records, otherwise the records assigned to a
+// new server may be removed by the removal requested by an old server with the same IP.
+var subDomainMutex sync.Mutex
+
+// releaseSubDomains removes the server from the owners of the subdomains matched, and marks the subdomains
+// without owners to remove.
+func releaseSubDomains(bkt *bolt.Bucket, serverTicket string, match func(domain string) bool) error {
+	var toUpdate []model.SubDomain
+	if err := bkt.ForEach(func(k, b []byte) error {
+		var sub model.SubDomain
+		if err := jsoniter.Unmarshal(b, &sub); err != nil {
+			return nil
+		}
+		if !match(sub.Domain) {
+			return nil
+		}
+		tickets := make([]string, 0, len(sub.ServerTickets))
+		for _, t := range sub.ServerTickets {
+			if t != serverTicket {
+				tickets = append(tickets, t)
+			}
+		}
+		if len(tickets) == len(sub.ServerTickets) {
+			return nil
+		}
+		sub.ServerTickets = tickets
+		if len(tickets) == 0 {
+			sub.Removing = true
+		}
+		toUpdate = append(toUpdate, sub)
+		return nil
+	}); err != nil {
+		return err
+	}
+	// do not modify the bucket in ForEach
+	for _, sub := range toUpdate {
+		b, err := jsoniter.Marshal(sub)
+		if err != nil {
+			return err
+		}
+		if err = bkt.Put([]byte(sub.Domain), b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TrackSubDomain adds the server to the owners of the subdomain, and releases the other subdomains of the server.
+func TrackSubDomain(wtx *bolt.Tx, domain string, server model.Server, chatIdentifier string) (err error) {
+	f := func(tx *bolt.Tx) error {
+		bkt, err := tx.CreateBucketIfNotExists([]byte(model.BucketSubDomain))
+		if err != nil {
+			return err
+		}
+		if err = releaseSubDomains(bkt, server.Ticket, func(d string) bool {
+			return d != domain
+		}); err != nil {
+			return err
+		}
+		sub := model.SubDomain{Domain: domain}
+		if b := bkt.Get([]byte(domain)); b != nil {
+			_ = jsoniter.Unmarshal(b, &sub)
+		}
+		tracked := false
+		for _, t := range sub.ServerTickets {
+			if t == server.Ticket {
+				tracked = true
+				break
+			}
+		}
+		if !tracked {
+			sub.ServerTickets = append(sub.ServerTickets, server.Ticket)
+		}
+		sub.ServerName = server.Name
+		sub.ChatIdentifier = chatIdentifier
+		sub.AssignedAt = time.Now()
+		sub.Removing = false
+		sub.RemoveAttempts = 0
+		sub.LastError = ""
+		b, err := jsoniter.Marshal(sub)
+		if err != nil {
+			return err
+		}
+		return bkt.Put([]byte(domain), b)
+	}
+	if wtx != nil {
+		err = f(wtx)
+	} else {
+		err = db.DB().Update(f)
+	}
+	if err != nil {
+		return fmt.Errorf("TrackSubDomain: %w", err)
+	}
+	return nil
+}
+
+// RequestRemoveSubDomains removes the server from the owners of its subdomains. The subdomains without owners
+// are marked to remove, and the records will be removed in background.
+func RequestRemoveSubDomains(wtx *bolt.Tx, serverTicket string) (err error) {
+	f := func(tx *bolt.Tx) error {
+		bkt := tx.Bucket([]byte(model.BucketSubDomain))
+		if bkt == nil {
+			return nil
+		}
+		return releaseSubDomains(bkt, serverTicket, func(string) bool {
+			return true
+		})
+	}
+	if wtx != nil {
+		err = f(wtx)
+	} else {
+		err = db.DB().Update(f)
+	}
+	if err != nil {
+		return fmt.Errorf("RequestRemoveSubDomains: %w", err)
+	}
+	return nil
+}
+
+// RequestRemoveSubDomain removes the server from the owners of the subdomain, which is marked to remove if it
+// has no owner. The subdomain is tracked to remove if it has not been tracked.
+func RequestRemoveSubDomain(wtx *bolt.Tx, domain string, server model.Server, chatIdentifier string) (err error) {
+	f := func(tx *bolt.Tx) error {
+		bkt, err := tx.CreateBucketIfNotExists([]byte(model.BucketSubDomain))
+		if err != nil {
+			return err
+		}
+		if bkt.Get([]byte(domain)) != nil {
+			return releaseSubDomains(bkt, server.Ticket, func(d string) bool {
+				return d == domain
+			})
+		}
+		// the records may be assigned before the subdomains are tracked
+		b, err := jsoniter.Marshal(model.SubDomain{
+			Domain:         domain,
+			ServerName:     server.Name,
+			ChatIdentifier: chatIdentifier,
+			AssignedAt:     time.Now(),
+			Removing:       true,
+		})
+		if err != nil {
+			return err
+		}
+		return bkt.Put([]byte(domain), b)
+	}
+	if wtx != nil {
+		err = f(wtx)
+	} else {
+		err = db.DB().Update(f)
+	}
+	if err != nil {
+		return fmt.Errorf("RequestRemoveSubDomain: %w", err)
+	}
+	return nil
+}
+
+// subDomainUsers returns the tickets of the servers with valid tickets using the subdomain.
+func subDomainUsers(tx *bolt.Tx, domain string) (tickets []string) {
+	bkt := tx.Bucket([]byte(model.BucketServer))
+	if bkt == nil {
+		return nil
+	}
+	_ = bkt.ForEach(func(k, b []byte) error {
+		var server model.Server
+		if err := jsoniter.Unmarshal(b, &server); err != nil {
+			return nil
+		}
+		if d, ok := ServerCertificateDomain(server); !ok || d != domain {
+			return nil
+		}
+		if _, err := GetValidTicketObj(tx, server.Ticket); err != nil {
+			return nil
+		}
+		tickets = append(tickets, server.Ticket)
+		return nil
+	})
+	return tickets
+}
+
+// confirmSubDomainRemoval checks the subdomain is still waiting to be removed and no valid server uses it.
+// If it is still in use, the servers using it become its owners and it is unmarked.
+func confirmSubDomainRemoval(tx *bolt.Tx, domain string) error {
+	bkt := tx.Bucket([]byte(model.BucketSubDomain))
+	if bkt == nil {
+		return db.ErrKeyNotFound
+	}
+	b := bkt.Get([]byte(domain))
+	if b == nil {
+		return db.ErrKeyNotFound
+	}
+	var sub model.SubDomain
+	if err := jsoniter.Unmarshal(b, &sub); err != nil {
+		return err
+	}
+	if !sub.Removing {
+		return ErrSubDomainInUse
+	}
+	tickets := subDomainUsers(tx, domain)
+	if len(tickets) == 0 {
+		return nil
+	}
+	// the owners are restored, otherwise the subdomain would never be removed after they are gone
+	sub.ServerTickets = tickets
+	sub.Removing = false
+	b, err := jsoniter.Marshal(sub)
+	if err != nil {
+		return err
+	}
+	if err = bkt.Put([]byte(domain), b); err != nil {
+		return err
+	}
+	return ErrSubDomainInUse
+}
+
+// RemoveSubDomainRecords removes the DNS records of the subdomain with retries. ErrSubDomainInUse is returned
+// if the subdomain is not waiting to be removed anymore.
+func RemoveSubDomainRecords(ctx context.Context, domain string) (err error) {
+	conf := config.GetConfig()
+	ns, err := nameserver.NewNameserver(conf.NameserverName, conf.NameserverToken)
+	if err != nil {
+		return fmt.Errorf("RemoveSubDomainRecords: %w", err)
+	}
+	subDomainMutex.Lock()
+	defer subDomainMutex.Unlock()
+	if err = db.DB().Update(func(tx *bolt.Tx) error {
+		return confirmSubDomainRemoval(tx, domain)
+	}); err != nil {
+		return fmt.Errorf("RemoveSubDomainRecords: %v: %w", domain, err)
+	}
+	for i := 0; i < 3; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("RemoveSubDomainRecords: %v: %w", domain, ctx.Err())
+			case <-time.After(time.Duration(i) * 5 * time.Second):
+			}
+		}
+		if err = ns.RemoveRecords(ctx, domain); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("RemoveSubDomainRecords: %v: %w", domain, err)
+}
+
+// UntrackSubDomain removes the subdomain if it is still waiting to be removed and has not been assigned again
+// since assignedAt.
+func UntrackSubDomain(wtx *bolt.Tx, domain string, assignedAt time.Time) (err error) {
+	f := func(tx *bolt.Tx) error {
+		bkt := tx.Bucket([]byte(model.BucketSubDomain))
+		if bkt == nil {
+			return nil
+		}
+		b := bkt.Get([]byte(domain))
+		if b == nil {
+			return nil
+		}
+		var sub model.SubDomain
+		if err := jsoniter.Unmarshal(b, &sub); err == nil && (!sub.AssignedAt.Equal(assignedAt) || !sub.Removing) {
+			// it has been assigned again
+			return nil
+		}
+		return bkt.Delete([]byte(domain))
+	}
+	if wtx != nil {
+		err = f(wtx)
+	} else {
+		err = db.DB().Update(f)
+	}
+	if err != nil {
+		return fmt.Errorf("UntrackSubDomain: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/daeuniverse/softwind/protocol"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/model"
+	jsoniter "github.com/json-iterator/go"
+)
+
+func getSubDomain(t *testing.T, tx *bolt.Tx, domain string) model.SubDomain {
+	var sub model.SubDomain
+	if err := jsoniter.Unmarshal(tx.Bucket([]byte(model.BucketSubDomain)).Get([]byte(domain)), &sub); err != nil {
+		t.Fatal(err)
+	}
+	return sub
+}
+
+func openTestDB(t *testing.T) *bolt.DB {
+	d, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { d.Close() })
+	return d
+}
+
+func put(t *testing.T, tx *bolt.Tx, bucket string, key string, v interface{}) {
+	bkt, err := tx.CreateBucketIfNotExists([]byte(bucket))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := jsoniter.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = bkt.Put([]byte(key), b); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSubDomainOwners(t *testing.T) {
+	if err := openTestDB(t).Update(func(tx *bolt.Tx) error {
+		// two servers share the subdomain of the same IP
+		if err := TrackSubDomain(tx, "a.example.com", model.Server{Ticket: "1", Name: "one"}, "chat"); err != nil {
+			return err
+		}
+		if err := TrackSubDomain(tx, "a.example.com", model.Server{Ticket: "2", Name: "two"}, "chat"); err != nil {
+			return err
+		}
+		if sub := getSubDomain(t, tx, "a.example.com"); !reflect.DeepEqual(sub.ServerTickets, []string{"1", "2"}) || sub.ServerName != "two" {
+			t.Fatalf("unexpected owners: %v (%v)", sub.ServerTickets, sub.ServerName)
+		}
+
+		if err := RequestRemoveSubDomains(tx, "1"); err != nil {
+			return err
+		}
+		if sub := getSubDomain(t, tx, "a.example.com"); sub.Removing || !reflect.DeepEqual(sub.ServerTickets, []string{"2"}) {
+			t.Fatalf("the subdomain should be kept for the other server: %+v", sub)
+		}
+
+		// the server moves to another IP
+		if err := TrackSubDomain(tx, "b.example.com", model.Server{Ticket: "2", Name: "two"}, "chat"); err != nil {
+			return err
+		}
+		if sub := getSubDomain(t, tx, "a.example.com"); !sub.Removing || len(sub.ServerTickets) != 0 {
+			t.Fatalf("the subdomain without owners should be removed: %+v", sub)
+		}
+
+		// assigned again
+		if err := TrackSubDomain(tx, "a.example.com", model.Server{Ticket: "3", Name: "three"}, "chat"); err != nil {
+			return err
+		}
+		if sub := getSubDomain(t, tx, "a.example.com"); sub.Removing || !reflect.DeepEqual(sub.ServerTickets, []string{"3"}) {
+			t.Fatalf("the subdomain should be kept for the new server: %+v", sub)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSubDomainInUseOnRemoval(t *testing.T) {
+	server := model.Server{
+		Ticket: "1",
+		Name:   "one",
+		Hosts:  "1.2.3.4",
+		Argument: model.Argument{
+			Protocol: protocol.ProtocolVMessTlsGrpc,
+		},
+	}
+	domain, ok := ServerCertificateDomain(server)
+	if !ok {
+		t.Fatal("the server should have a subdomain")
+	}
+	if err := openTestDB(t).Update(func(tx *bolt.Tx) error {
+		put(t, tx, model.BucketTicket, server.Ticket, model.Ticket{Ticket: server.Ticket, Type: model.TicketTypeServer})
+		put(t, tx, model.BucketServer, server.Ticket, server)
+		// the records were assigned before the server was tracked
+		if err := RequestRemoveSubDomain(tx, domain, server, "chat"); err != nil {
+			return err
+		}
+		if err := confirmSubDomainRemoval(tx, domain); !errors.Is(err, ErrSubDomainInUse) {
+			t.Fatalf("the subdomain in use should not be removed: %v", err)
+		}
+		if sub := getSubDomain(t, tx, domain); sub.Removing || !reflect.DeepEqual(sub.ServerTickets, []string{"1"}) {
+			t.Fatalf("the server using the subdomain should become its owner: %+v", sub)
+		}
+
+		// revoked
+		if err := tx.Bucket([]byte(model.BucketServer)).Delete([]byte(server.Ticket)); err != nil {
+			return err
+		}
+		if err := RequestRemoveSubDomains(tx, server.Ticket); err != nil {
+			return err
+		}
+		if sub := getSubDomain(t, tx, domain); !sub.Removing {
+			t.Fatalf("the subdomain should be removed after the server is revoked: %+v", sub)
+		}
+		if err := confirmSubDomainRemoval(tx, domain); err != nil {
+			t.Fatalf("the subdomain should be removed: %v", err)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
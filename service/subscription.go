@@ -0,0 +1,286 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/daeuniverse/softwind/protocol"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/BitterJohn/server"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/common"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/config"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/model"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/pkg/log"
+	"github.com/rs/dnscache"
+)
+
+var cachedResolver = dnscache.Resolver{}
+
+type SubscriptionOption struct {
+	// V4V6Mask filters the hosts. 1 for ipv4 and 2 for ipv6.
+	V4V6Mask uint8
+	// TypeMask filters the nodes. 1 for endpoints and 2 for relays.
+	TypeMask  uint8
+	ShowQuota bool
+	NoQuota   bool
+}
+
+func NameToShow(server *model.Server, showQuota bool, noQuota bool) string {
+	remaining, limited := server.BandwidthLimit.RemainingKiB()
+	if !limited {
+		return server.Name
+	}
+	fRemainingGiB := float64(remaining) / 1024 / 1024
+	// do not show if there is adequate bandwidth
+	if noQuota || (fRemainingGiB > 500 && !showQuota) {
+		return server.Name
+	}
+	fields := regexp.MustCompile(`^\[(.+)]\s*(.+)$`).FindStringSubmatch(server.Name)
+	if len(fields) == 3 {
+		// [100Mbps] Racknerd -> [100Mbps 472.7GiB] Racknerd
+		return fmt.Sprintf("[%v %.1fGB] %v", fields[1], fRemainingGiB, fields[2])
+	}
+	// Racknerd -> [472.7GiB] Racknerd
+	return fmt.Sprintf("[%.1fGB] %v", fRemainingGiB, server.Name)
+}
+
+// nodeSni returns the TLS server name of the node of given server.
+func nodeSni(svr *model.Server) string {
+	switch {
+	case svr.Argument.Protocol == protocol.ProtocolJuicity:
+		return server.JuicityDomain
+	case svr.Argument.Protocol == model.ProtocolVLESSReality, svr.Argument.Protocol == model.ProtocolHysteria2:
+		// REALITY borrows the handshake of the website given by the server, and the certificate of
+		// Hysteria2 is pinned
+		return common.SimplyGetParam(svr.Argument.Method, "sni")
+	case svr.Argument.Protocol.WithTLS():
+		sni, _ := common.HostToSNI(model.GetFirstHost(svr.Hosts), config.GetConfig().Host)
+		return sni
+	default:
+		return ""
+	}
+}
+
+// BuildSubscription generates the subscription nodes of endpoints and relays for given user or relay ticket.
+func BuildSubscription(tx *bolt.Tx, ticObj model.Ticket, opt SubscriptionOption) (sub *model.Subscription, err error) {
+	switch ticObj.Type {
+	case model.TicketTypeUser, model.TicketTypeRelay:
+	default:
+		return nil, fmt.Errorf("bad request")
+	}
+	if opt.V4V6Mask == 0 {
+		opt.V4V6Mask = 1 | 2
+	}
+	if opt.TypeMask == 0 {
+		opt.TypeMask = 1 | 2
+	}
+	// get servers
+	servers, err := GetServersByChatIdentifier(tx, ticObj.ChatIdentifier, true)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(servers, func(i, j int) bool {
+		return servers[i].Name < servers[j].Name
+	})
+
+	var relays []model.Server
+	var svrs []model.Server
+	for _, server := range servers {
+		if server.FailureCount >= model.MaxFailureCount {
+			// do not return disconnected server
+			continue
+		}
+		svrTic, err := GetValidTicketObj(tx, server.Ticket)
+		if err != nil {
+			log.Warn("BuildSubscription: GetValidTicketObj: %v", err)
+			continue
+		}
+		switch svrTic.Type {
+		case model.TicketTypeRelay:
+			relays = append(relays, server)
+		case model.TicketTypeServer:
+			svrs = append(svrs, server)
+		}
+	}
+
+	type candidate struct {
+		node  model.SubscriptionNode
+		relay bool
+		// rtt is from the last relay to the server. Zero means not measured.
+		rtt time.Duration
+	}
+	var candidates []candidate
+	if (opt.TypeMask & 1) == 1 {
+		for i := range svrs {
+			svr := &svrs[i]
+			for _, host := range strings.Split(svr.Hosts, ",") {
+				candidates = append(candidates, candidate{
+					node: model.SubscriptionNode{
+						Name:     NameToShow(svr, opt.ShowQuota, opt.NoQuota),
+						Host:     host,
+						Port:     svr.Port,
+						Argument: model.GetUserArgument(svr.Ticket, ticObj.Ticket, svr.Argument),
+						Sni:      nodeSni(svr),
+						Chain:    []string{svr.Name},
+						UpMbps:   svr.BandwidthLimit.DownlinkMbps,
+						DownMbps: svr.BandwidthLimit.UplinkMbps,
+					},
+				})
+			}
+		}
+	}
+	if (opt.TypeMask & 2) == 2 {
+		chat, err := GetChat(tx, ticObj.ChatIdentifier)
+		if err != nil {
+			return nil, err
+		}
+		probes := make(map[string]*model.RelayProbe)
+		for _, relay := range relays {
+			probes[relay.Ticket] = GetRelayProbe(tx, relay.Ticket)
+		}
+		for _, chain := range GetRelayChains(relays, MaxRelayHops()) {
+			first := &chain[0]
+			var names []string
+			for i := range chain {
+				names = append(names, NameToShow(&chain[i], opt.ShowQuota, opt.NoQuota))
+			}
+			relayTickets := chain.Tickets()
+			for j := range svrs {
+				svr := &svrs[j]
+				if !chain.CanReach(svr) {
+					continue
+				}
+				rtt, _ := probes[chain[len(chain)-1].Ticket].RTT(svr.Ticket)
+				if maxRTT := chat.GetMaxRelayRTT(); maxRTT > 0 && rtt > maxRTT {
+					// prune slow chains
+					continue
+				}
+				name := fmt.Sprintf("%v -> %v", strings.Join(names, " -> "), NameToShow(svr, opt.ShowQuota, opt.NoQuota))
+				for _, host := range strings.Split(first.Hosts, ",") {
+					var chainNames []string
+					for _, relay := range chain {
+						chainNames = append(chainNames, relay.Name)
+					}
+					candidates = append(candidates, candidate{
+						node: model.SubscriptionNode{
+							Name:     name,
+							Host:     host,
+							Port:     first.Port,
+							Argument: model.GetChainUserArgument(svr.Ticket, relayTickets, ticObj.Ticket, first.Argument),
+							Sni:      nodeSni(first),
+							Chain:    append(chainNames, svr.Name),
+							UpMbps:   first.BandwidthLimit.DownlinkMbps,
+							DownMbps: first.BandwidthLimit.UplinkMbps,
+						},
+						relay: true,
+						rtt:   rtt,
+					})
+				}
+			}
+		}
+	}
+
+	// faster relay chains go first, and chains not measured go last
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].relay != candidates[j].relay {
+			return !candidates[i].relay
+		}
+		if candidates[i].rtt == 0 || candidates[j].rtt == 0 {
+			return candidates[j].rtt == 0 && candidates[i].rtt != 0
+		}
+		return candidates[i].rtt < candidates[j].rtt
+	})
+
+	// filter hosts concurrently because it costs time
+	valid := make([]bool, len(candidates))
+	var wg sync.WaitGroup
+	for i := range candidates {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			valid[i] = ValidNetwork(candidates[i].node.Host, opt.V4V6Mask)
+		}(i)
+	}
+	wg.Wait()
+
+	sub = &model.Subscription{
+		Ticket:  ticObj,
+		Servers: servers,
+	}
+	for i, c := range candidates {
+		if !valid[i] {
+			continue
+		}
+		if c.relay {
+			sub.Relays = append(sub.Relays, c.node)
+		} else {
+			sub.Endpoints = append(sub.Endpoints, c.node)
+		}
+	}
+	return sub, nil
+}
+
+// SubscriptionUserinfo generates the value of the Subscription-Userinfo header from the bandwidth limits of
+// servers and the expiration of the ticket.
+// The total is the usage plus the remaining of the limited servers, thus zero means no limit.
+func SubscriptionUserinfo(sub *model.Subscription) string {
+	var upload, download, remaining int64
+	var limited bool
+	for _, server := range sub.Servers {
+		uplink, downlink := server.BandwidthLimit.UsedKiB()
+		upload += uplink
+		download += downlink
+		if r, ok := server.BandwidthLimit.RemainingKiB(); ok {
+			remaining += r
+			limited = true
+		}
+	}
+	var total int64
+	if limited {
+		total = upload + download + remaining
+	}
+	fields := []string{
+		fmt.Sprintf("upload=%v", upload*1024),
+		fmt.Sprintf("download=%v", download*1024),
+		fmt.Sprintf("total=%v", total*1024),
+	}
+	if !sub.Ticket.ExpireAt.IsZero() {
+		fields = append(fields, fmt.Sprintf("expire=%v", sub.Ticket.ExpireAt.Unix()))
+	}
+	return strings.Join(fields, "; ")
+}
+
+func ValidNetwork(server string, v4v6Mask uint8) (ok bool) {
+	return ServerNetType(server)&v4v6Mask > 0
+}
+
+func ServerNetType(server string) (typ uint8) {
+	if ip, err := netip.ParseAddr(server); err != nil {
+		addrs, err := cachedResolver.LookupHost(context.Background(), server)
+		if err != nil {
+			// cannot resolve
+			return 1 | 2
+		}
+		for _, a := range addrs {
+			if net.ParseIP(a).To4() != nil {
+				typ |= 1
+			} else {
+				typ |= 2
+			}
+		}
+	} else if ip.IsLoopback() {
+		typ = 1 | 2
+	} else if ip.Is4() {
+		typ = 1
+	} else {
+		typ = 2
+	}
+	return typ
+}
@@ -146,12 +146,16 @@ func (b *ServerSyncBox) SyncBackground() {
 					})
 					if err != nil {
 						log.Info("SyncBackground: %v: %v", svr.Name, err)
+						ObserveSync(svr, "failure")
 						return
 					}
 					defer func() {
 						failed := err != nil && !common.IsCanceled(err)
 						if failed {
 							log.Info("Retry the sync after seeing the server %v next time: %v", svr.Name, err.Error())
+							ObserveSync(svr, "failure")
+						} else if err == nil {
+							ObserveSync(svr, "success")
 						}
 						_ = setSyncNextSeen(ticket, failed)
 					}()
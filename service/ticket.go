@@ -13,12 +13,14 @@ import (
 
 var ErrInvalidTicket = fmt.Errorf("invalid ticket")
 
-// SaveTicket saves the given ticket to the database and sets the expiration time to the next month
-func SaveTicket(wtx *bolt.Tx, ticket string, typ model.TicketType, chatIdentifier string) (tic model.Ticket, err error) {
+// SaveTicket saves the given ticket to the database and sets the expiration time to the next month.
+// The issuer of a renewed ticket is kept.
+func SaveTicket(wtx *bolt.Tx, ticket string, typ model.TicketType, chatIdentifier string, issuer string) (tic model.Ticket, err error) {
 	tic = model.Ticket{
 		Ticket:         ticket,
 		ChatIdentifier: chatIdentifier,
 		Type:           typ,
+		Issuer:         issuer,
 	}
 	// server ticket never expire
 	switch typ {
@@ -36,6 +38,16 @@ func SaveTicket(wtx *bolt.Tx, ticket string, typ model.TicketType, chatIdentifie
 		if err != nil {
 			return err
 		}
+		// keep the quota for renewal
+		if bOld := bkt.Get([]byte(ticket)); bOld != nil {
+			var old model.Ticket
+			if err := jsoniter.Unmarshal(bOld, &old); err == nil && old.Type == typ {
+				tic.QuotaGiB = old.QuotaGiB
+				if old.Issuer != "" {
+					tic.Issuer = old.Issuer
+				}
+			}
+		}
 		b, err := jsoniter.Marshal(&tic)
 		if err != nil {
 			return err
@@ -126,6 +138,44 @@ func GetValidTickets(tx *bolt.Tx) (tickets []model.Ticket) {
 	return tickets
 }
 
+// SetTicketQuota sets the monthly quota of the user ticket. Zero means no limit.
+func SetTicketQuota(wtx *bolt.Tx, ticket string, chatIdentifier string, quotaGiB int64) (tic model.Ticket, err error) {
+	if quotaGiB < 0 {
+		return model.Ticket{}, fmt.Errorf("quota cannot be negative")
+	}
+	f := func(tx *bolt.Tx) error {
+		tic, err = GetValidTicketObj(tx, ticket)
+		if err != nil {
+			return err
+		}
+		if tic.ChatIdentifier != chatIdentifier || tic.Type != model.TicketTypeUser {
+			return ErrInvalidTicket
+		}
+		tic.QuotaGiB = quotaGiB
+		bkt, err := tx.CreateBucketIfNotExists([]byte(model.BucketTicket))
+		if err != nil {
+			return err
+		}
+		b, err := jsoniter.Marshal(&tic)
+		if err != nil {
+			return err
+		}
+		if err = bkt.Put([]byte(ticket), b); err != nil {
+			return err
+		}
+		return RefreshQuotaState(tx, ticket)
+	}
+	if wtx != nil {
+		err = f(wtx)
+	} else {
+		err = db.DB().Update(f)
+	}
+	if err != nil {
+		return model.Ticket{}, err
+	}
+	return tic, nil
+}
+
 func RevokeTicket(wtx *bolt.Tx, ticket string, chatIdentifier string) (err error) {
 	f := func(tx *bolt.Tx) error {
 		ticObj, err := GetValidTicketObj(tx, ticket)
@@ -143,6 +193,9 @@ func RevokeTicket(wtx *bolt.Tx, ticket string, chatIdentifier string) (err error
 				// so ignore the error.
 				_ = svrBkt.Delete([]byte(ticket))
 			}
+			if err := RequestRemoveSubDomains(tx, ticket); err != nil {
+				return err
+			}
 		default:
 		}
 		ticBkt, err := tx.CreateBucketIfNotExists([]byte(model.BucketTicket))
@@ -156,3 +209,30 @@ func RevokeTicket(wtx *bolt.Tx, ticket string, chatIdentifier string) (err error
 	}
 	return db.DB().Update(f)
 }
+
+// RevokeTicketAs revokes the ticket by the member. Tickets issued by others can only be revoked by operators.
+func RevokeTicketAs(wtx *bolt.Tx, ticket string, chatIdentifier string, member string) (err error) {
+	f := func(tx *bolt.Tx) error {
+		ticObj, err := GetValidTicketObj(tx, ticket)
+		if err != nil {
+			return err
+		}
+		if ticObj.ChatIdentifier != chatIdentifier {
+			return ErrInvalidTicket
+		}
+		if member == "" || ticObj.Issuer != member {
+			role, err := GetRole(tx, chatIdentifier, member)
+			if err != nil {
+				return err
+			}
+			if role < model.RoleOperator {
+				return fmt.Errorf("%w: %v is required to revoke tickets of others", model.ErrPermissionDenied, model.RoleOperator)
+			}
+		}
+		return RevokeTicket(tx, ticket, chatIdentifier)
+	}
+	if wtx != nil {
+		return f(wtx)
+	}
+	return db.DB().Update(f)
+}
@@ -0,0 +1,282 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/common"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/db"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/model"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/pkg/log"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// GetPassageOwners returns the mapping from the hashes of passage inbounds of the server to user tickets.
+func GetPassageOwners(tx *bolt.Tx, serverTicket string) (owners map[string]string, err error) {
+	owners = make(map[string]string)
+	f := func(tx *bolt.Tx) error {
+		serverTicketObj, err := GetValidTicketObj(tx, serverTicket)
+		if err != nil {
+			return err
+		}
+		serverObj, err := GetServerByTicket(tx, serverTicket)
+		if err != nil {
+			return err
+		}
+		var userTickets []string
+		var servers []model.Server
+		var relays []model.Server
+		for _, tic := range GetValidTickets(tx) {
+			if tic.ChatIdentifier != serverTicketObj.ChatIdentifier {
+				continue
+			}
+			switch tic.Type {
+			case model.TicketTypeUser:
+				userTickets = append(userTickets, tic.Ticket)
+			case model.TicketTypeServer, model.TicketTypeRelay:
+				if serverTicketObj.Type != model.TicketTypeRelay || tic.Ticket == serverTicket {
+					continue
+				}
+				svr, err := GetServerByTicket(tx, tic.Ticket)
+				if err != nil {
+					continue
+				}
+				if tic.Type == model.TicketTypeServer {
+					servers = append(servers, svr)
+				} else {
+					relays = append(relays, svr)
+				}
+			}
+		}
+		switch serverTicketObj.Type {
+		case model.TicketTypeServer:
+			for _, userTicket := range userTickets {
+				owners[model.GetUserArgument(serverTicket, userTicket, serverObj.Argument).Hash()] = userTicket
+			}
+		case model.TicketTypeRelay:
+			chains := GetRelayChainsFrom(serverObj, relays, MaxRelayHops())
+			for _, svr := range servers {
+				for _, chain := range chains {
+					relayTickets := chain.Tickets()
+					for _, userTicket := range userTickets {
+						owners[model.GetChainUserArgument(svr.Ticket, relayTickets, userTicket, serverObj.Argument).Hash()] = userTicket
+					}
+				}
+			}
+		}
+		return nil
+	}
+	if tx != nil {
+		if err = f(tx); err != nil {
+			return nil, fmt.Errorf("GetPassageOwners: %w", err)
+		}
+		return owners, nil
+	}
+	if err = db.DB().View(f); err != nil {
+		return nil, fmt.Errorf("GetPassageOwners: %w", err)
+	}
+	return owners, nil
+}
+
+// AddPassageUsages accumulates the passage usages reported by the server to the usages of user tickets.
+// The usages of passages from relays are ignored here because they are accounted by the relays.
+func AddPassageUsages(wtx *bolt.Tx, server model.Server, usages []model.PassageUsage) (err error) {
+	f := func(tx *bolt.Tx) error {
+		owners, err := GetPassageOwners(tx, server.Ticket)
+		if err != nil {
+			return err
+		}
+		serverTicketObj, err := GetValidTicketObj(tx, server.Ticket)
+		if err != nil {
+			return err
+		}
+		chat, err := GetChat(tx, serverTicketObj.ChatIdentifier)
+		if err != nil {
+			return err
+		}
+		bkt, err := tx.CreateBucketIfNotExists([]byte(model.BucketUsage))
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		var toSync bool
+		for _, u := range usages {
+			ticket, ok := owners[u.InHash]
+			if !ok {
+				continue
+			}
+			var usage model.TicketUsage
+			if b := bkt.Get([]byte(ticket)); b != nil {
+				if err := jsoniter.Unmarshal(b, &usage); err != nil {
+					log.Warn("AddPassageUsages: %v", err)
+				}
+			}
+			usage.Ticket = ticket
+			usage.ChatIdentifier = serverTicketObj.ChatIdentifier
+			usage.Roll(now, chat.GetUsageResetDay())
+			usage.UplinkKiB += u.UplinkKiB
+			usage.DownlinkKiB += u.DownlinkKiB
+			if UpdateQuotaState(tx, &usage, now) {
+				toSync = true
+			}
+			b, err := jsoniter.Marshal(usage)
+			if err != nil {
+				return err
+			}
+			if err = bkt.Put([]byte(ticket), b); err != nil {
+				return err
+			}
+		}
+		if toSync {
+			reqSyncChatLater(serverTicketObj.ChatIdentifier)
+		}
+		return nil
+	}
+	if wtx != nil {
+		if err = f(wtx); err != nil {
+			return fmt.Errorf("AddPassageUsages: %w", err)
+		}
+		return nil
+	}
+	if err = db.DB().Update(f); err != nil {
+		return fmt.Errorf("AddPassageUsages: %w", err)
+	}
+	return nil
+}
+
+// GetTicketUsage returns the usage of the user ticket. The usage of a ticket without traffic is zero.
+func GetTicketUsage(tx *bolt.Tx, ticket string) (usage model.TicketUsage, err error) {
+	f := func(tx *bolt.Tx) error {
+		tic, err := GetTicketObj(tx, ticket)
+		if err != nil {
+			return err
+		}
+		usage = model.TicketUsage{
+			Ticket:         tic.Ticket,
+			ChatIdentifier: tic.ChatIdentifier,
+		}
+		bkt := tx.Bucket([]byte(model.BucketUsage))
+		if bkt == nil {
+			return nil
+		}
+		b := bkt.Get([]byte(ticket))
+		if b == nil {
+			return nil
+		}
+		if err = jsoniter.Unmarshal(b, &usage); err != nil {
+			return err
+		}
+		chat, err := GetChat(tx, tic.ChatIdentifier)
+		if err != nil {
+			return err
+		}
+		usage.Roll(time.Now(), chat.GetUsageResetDay())
+		return nil
+	}
+	if tx != nil {
+		err = f(tx)
+	} else {
+		err = db.DB().View(f)
+	}
+	if err != nil {
+		return model.TicketUsage{}, fmt.Errorf("GetTicketUsage: %w", err)
+	}
+	return usage, nil
+}
+
+// IsTicketQuotaExceeded reports if the user ticket has exceeded its quota in the current cycle.
+func IsTicketQuotaExceeded(tx *bolt.Tx, tic model.Ticket) bool {
+	if tic.QuotaGiB <= 0 {
+		return false
+	}
+	usage, err := GetTicketUsage(tx, tic.Ticket)
+	if err != nil {
+		log.Warn("IsTicketQuotaExceeded: %v", err)
+		return false
+	}
+	return usage.Exceeded(tic.QuotaGiB)
+}
+
+// UpdateQuotaState rolls the usage and updates its CutOff according to the quota of the ticket.
+// A feed will be added if the ticket is cut off or restored, and changed is true to indicate a sync is required.
+func UpdateQuotaState(wtx *bolt.Tx, usage *model.TicketUsage, now time.Time) (changed bool) {
+	tic, err := GetTicketObj(wtx, usage.Ticket)
+	if err != nil {
+		return false
+	}
+	chat, err := GetChat(wtx, tic.ChatIdentifier)
+	if err != nil {
+		log.Warn("UpdateQuotaState: %v", err)
+		return false
+	}
+	usage.Roll(now, chat.GetUsageResetDay())
+	exceeded := usage.Exceeded(tic.QuotaGiB)
+	if exceeded == usage.CutOff {
+		return false
+	}
+	usage.CutOff = exceeded
+	var action TicketAction = TicketActionQuotaRestored
+	if exceeded {
+		action = TicketActionQuotaExceeded
+	}
+	log.Info("%v: ticket %v of chat %v", action, tic.Masked(), tic.ChatIdentifier)
+	if err = AddFeedTicket(wtx, tic, *usage, action); err != nil {
+		log.Warn("AddFeedTicket: %v", err)
+	}
+	return true
+}
+
+// RefreshQuotaState updates the quota state of the ticket in the database, and requests sync if it changed.
+func RefreshQuotaState(wtx *bolt.Tx, ticket string) (err error) {
+	f := func(tx *bolt.Tx) error {
+		usage, err := GetTicketUsage(tx, ticket)
+		if err != nil {
+			return err
+		}
+		if !UpdateQuotaState(tx, &usage, time.Now()) {
+			return nil
+		}
+		bkt, err := tx.CreateBucketIfNotExists([]byte(model.BucketUsage))
+		if err != nil {
+			return err
+		}
+		b, err := jsoniter.Marshal(usage)
+		if err != nil {
+			return err
+		}
+		if err = bkt.Put([]byte(ticket), b); err != nil {
+			return err
+		}
+		reqSyncChatLater(usage.ChatIdentifier)
+		return nil
+	}
+	if wtx != nil {
+		err = f(wtx)
+	} else {
+		err = db.DB().Update(f)
+	}
+	if err != nil {
+		return fmt.Errorf("RefreshQuotaState: %w", err)
+	}
+	return nil
+}
+
+// reqSyncChatLater asynchronously requests the sync to make sure it will happen after the transaction.
+func reqSyncChatLater(chatIdentifier string) {
+	time.AfterFunc(1*time.Second, func() {
+		// do not pass in tx here due to async
+		if e := ReqSyncPassagesByChatIdentifier(nil, chatIdentifier, true); e != nil {
+			log.Warn("ReqSyncPassagesByChatIdentifier: %v", e)
+		}
+	})
+}
+
+// IsUsageOrphan reports if the ticket of the usage does not exist or has been expired for a long time.
+func IsUsageOrphan(tx *bolt.Tx, usage model.TicketUsage) bool {
+	tic, err := GetTicketObj(tx, usage.Ticket)
+	if err != nil {
+		return true
+	}
+	return common.Expired(tic.ExpireAt.Add(7 * 24 * time.Hour))
+}
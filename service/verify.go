@@ -56,8 +56,9 @@ func NewVerification(wtx *bolt.Tx, chatIdentifier string) (verificationCode stri
 	return verificationCode, nil
 }
 
-// Verify verifies if given verificationCode and chatIdentifier can pass the verification
-func Verify(wtx *bolt.Tx, verificationCode string, chatIdentifier string) error {
+// Verify verifies if given verificationCode and chatIdentifier can pass the verification.
+// The member and its role are recorded in the verification.
+func Verify(wtx *bolt.Tx, verificationCode string, chatIdentifier string, member string) error {
 	f := func(tx *bolt.Tx) error {
 		bkt, err := tx.CreateBucketIfNotExists([]byte(model.BucketVerification))
 		if err != nil {
@@ -84,7 +85,13 @@ func Verify(wtx *bolt.Tx, verificationCode string, chatIdentifier string) error
 		if verification.Progress != model.VerificationWaiting {
 			return fmt.Errorf("pass already")
 		}
+		role, err := GetRole(tx, chatIdentifier, member)
+		if err != nil {
+			return err
+		}
 		verification.Progress = model.VerificationDone
+		verification.Member = member
+		verification.Role = role
 		verification.ExpireAt = time.Now().Add(2 * time.Minute)
 		b, err := jsoniter.Marshal(verification)
 		if err != nil {
@@ -102,6 +109,25 @@ func Verify(wtx *bolt.Tx, verificationCode string, chatIdentifier string) error
 
 // Verified check if given verificationCode and chatIdentifier verification has passed
 func Verified(wtx *bolt.Tx, verificationCode string, chatIdentifier string) error {
+	_, err := GetVerified(wtx, verificationCode, chatIdentifier)
+	return err
+}
+
+// VerifiedAs check if given verificationCode and chatIdentifier verification has passed by a member
+// with the role or a greater one.
+func VerifiedAs(wtx *bolt.Tx, verificationCode string, chatIdentifier string, role model.Role) (verification model.Verification, err error) {
+	verification, err = GetVerified(wtx, verificationCode, chatIdentifier)
+	if err != nil {
+		return model.Verification{}, err
+	}
+	if verification.Role < role {
+		return model.Verification{}, fmt.Errorf("%w: %v is required", model.ErrPermissionDenied, role)
+	}
+	return verification, nil
+}
+
+// GetVerified returns the verification if given verificationCode and chatIdentifier verification has passed
+func GetVerified(wtx *bolt.Tx, verificationCode string, chatIdentifier string) (verification model.Verification, err error) {
 	f := func(tx *bolt.Tx) error {
 		bkt, err := tx.CreateBucketIfNotExists([]byte(model.BucketVerification))
 		if err != nil {
@@ -112,7 +138,6 @@ func Verified(wtx *bolt.Tx, verificationCode string, chatIdentifier string) erro
 		if val == nil {
 			return fmt.Errorf("invalid verification code")
 		}
-		var verification model.Verification
 		if err := jsoniter.Unmarshal(val, &verification); err != nil {
 			log.Warn("%v", err)
 			return fmt.Errorf("internal error")
@@ -131,7 +156,12 @@ func Verified(wtx *bolt.Tx, verificationCode string, chatIdentifier string) erro
 		return nil
 	}
 	if wtx != nil {
-		return f(wtx)
+		err = f(wtx)
+	} else {
+		err = db.DB().Update(f)
 	}
-	return db.DB().Update(f)
+	if err != nil {
+		return model.Verification{}, err
+	}
+	return verification, nil
 }
@@ -2,6 +2,8 @@ package controller
 
 import (
 	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/common"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/model"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/service"
 	"github.com/gin-gonic/gin"
 	"path"
 	"strings"
@@ -22,3 +24,52 @@ func GetChat(ctx *gin.Context) {
 		common.ResponseBadRequestError(ctx)
 	}
 }
+
+// PostChatSetting will update the settings of the chat. Settings not given will be kept.
+func PostChatSetting(c *gin.Context) {
+	var req struct {
+		VerificationCode  string
+		UsageResetDay     *int
+		RelayHostPolicy   *model.OutHostPolicy
+		MaxRelayRTTMillis *int64
+	}
+	if err := c.ShouldBindJSON(&req); err != nil ||
+		req.UsageResetDay != nil && (*req.UsageResetDay < 0 || *req.UsageResetDay > model.MaxUsageResetDay) ||
+		req.RelayHostPolicy != nil && !req.RelayHostPolicy.IsValid() ||
+		req.MaxRelayRTTMillis != nil && *req.MaxRelayRTTMillis < 0 {
+		common.ResponseBadRequestError(c)
+		return
+	}
+	chatIdentifier := c.Param("ChatIdentifier")
+	if _, err := service.VerifiedAs(nil, req.VerificationCode, chatIdentifier, model.RoleOperator); err != nil {
+		common.ResponseError(c, err)
+		return
+	}
+	chat, err := service.GetChat(nil, chatIdentifier)
+	if err != nil {
+		common.ResponseError(c, err)
+		return
+	}
+	if req.UsageResetDay != nil {
+		chat.UsageResetDay = *req.UsageResetDay
+	}
+	if req.MaxRelayRTTMillis != nil {
+		chat.MaxRelayRTTMillis = *req.MaxRelayRTTMillis
+	}
+	var relayHostPolicyChanged bool
+	if req.RelayHostPolicy != nil && *req.RelayHostPolicy != chat.GetRelayHostPolicy() {
+		chat.RelayHostPolicy = *req.RelayHostPolicy
+		relayHostPolicyChanged = true
+	}
+	if err = service.SaveChat(nil, chat); err != nil {
+		common.ResponseError(c, err)
+		return
+	}
+	if relayHostPolicyChanged {
+		if err = service.ReqSyncPassagesByChatIdentifier(nil, chatIdentifier, true); err != nil {
+			common.ResponseError(c, err)
+			return
+		}
+	}
+	common.ResponseSuccess(c, chat)
+}
@@ -56,15 +56,17 @@ func PostRegister(c *gin.Context) {
 	// required info
 	if req.Hosts == "" ||
 		req.Port == 0 ||
-		!req.Argument.Protocol.Valid() ||
+		!model.ProtocolValid(req.Argument.Protocol) ||
 		req.Name == "" ||
 		hostsValidator(req.Hosts) != nil {
-		if !req.Argument.Protocol.Valid() {
+		if !model.ProtocolValid(req.Argument.Protocol) {
 			log.Debug("Register: bad request: %v", req)
 		}
 		common.ResponseBadRequestError(c)
 		return
 	}
+	// only the operator can override NoRelay
+	req.NoRelayOverridden = false
 	// verify the server ticket
 	ticObj := c.MustGet("TicketObj").(*model.Ticket)
 	switch ticObj.Type {
@@ -123,13 +125,21 @@ func PostRegister(c *gin.Context) {
 		if err = service.ReqSyncPassagesByServer(nil, req.Ticket, false); err != nil {
 			return
 		}
+		// the restarted server may lose the certificate, thus deliver it anyway
+		if service.ACMEEnabled() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+			defer cancel()
+			if e := service.ServeCertificate(ctx, req, true); e != nil {
+				log.Warn("%v", e)
+			}
+		}
 	}(req, ticObj.ChatIdentifier)
 
 	// assign subdomain for tls
 	if conf := config.GetConfig(); conf.NameserverName != "" && conf.NameserverToken != "" && req.Argument.Protocol.WithTLS() {
 		host := model.GetFirstHost(req.Hosts)
 		if ip, e := netip.ParseAddr(host); e == nil {
-			if e = service.AssignSubDomain(ip); e != nil {
+			if e = service.AssignSubDomain(ip, req, ticObj.ChatIdentifier); e != nil {
 				log.Warn("failed to assign subdomain: %v", e)
 			}
 		}
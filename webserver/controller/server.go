@@ -0,0 +1,146 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/common"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/model"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/service"
+	"github.com/gin-gonic/gin"
+)
+
+// GetServers returns the statuses of servers and relays of the chat
+func GetServers(c *gin.Context) {
+	var query struct {
+		VerificationCode string
+	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		common.ResponseBadRequestError(c)
+		return
+	}
+	chatIdentifier := c.Param("ChatIdentifier")
+	verification, err := service.GetVerified(nil, query.VerificationCode, chatIdentifier)
+	if err != nil {
+		common.ResponseError(c, err)
+		return
+	}
+	statuses, err := service.GetServerStatuses(nil, chatIdentifier)
+	if err != nil {
+		common.ResponseError(c, err)
+		return
+	}
+	if verification.Role < model.RoleOperator {
+		// server tickets are secrets of the operators
+		for i := range statuses {
+			statuses[i].Ticket = ""
+		}
+	}
+	common.ResponseSuccess(c, statuses)
+}
+
+// GetChatStatus returns the statuses of servers and relays of the chat for members
+func GetChatStatus(c *gin.Context) {
+	statuses, err := service.GetServerStatuses(nil, c.Param("ChatIdentifier"))
+	if err != nil {
+		common.ResponseError(c, err)
+		return
+	}
+	// server tickets are secrets of the operators
+	for i := range statuses {
+		statuses[i].Ticket = ""
+	}
+	common.ResponseSuccess(c, statuses)
+}
+
+// DeleteServer revokes the server or relay and removes it from the chat
+func DeleteServer(c *gin.Context) {
+	var query struct {
+		VerificationCode string
+	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		common.ResponseBadRequestError(c)
+		return
+	}
+	chatIdentifier := c.Param("ChatIdentifier")
+	if _, err := service.VerifiedAs(nil, query.VerificationCode, chatIdentifier, model.RoleOperator); err != nil {
+		common.ResponseError(c, err)
+		return
+	}
+	if err := service.RemoveServer(nil, c.Param("Ticket"), chatIdentifier); err != nil {
+		common.ResponseError(c, err)
+		return
+	}
+	// the others should stop forwarding to the removed one
+	if err := service.ReqSyncPassagesByChatIdentifier(nil, chatIdentifier, true); err != nil {
+		common.ResponseError(c, fmt.Errorf("ReqSyncPassagesByChatIdentifier: %v", err))
+		return
+	}
+	common.ResponseSuccess(c, nil)
+}
+
+// PostServerNoRelay sets whether the server can be relayed
+func PostServerNoRelay(c *gin.Context) {
+	var req struct {
+		VerificationCode string
+		NoRelay          bool
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ResponseBadRequestError(c)
+		return
+	}
+	chatIdentifier := c.Param("ChatIdentifier")
+	if _, err := service.VerifiedAs(nil, req.VerificationCode, chatIdentifier, model.RoleOperator); err != nil {
+		common.ResponseError(c, err)
+		return
+	}
+	ticket := c.Param("Ticket")
+	server, err := service.SetServerNoRelay(nil, ticket, chatIdentifier, req.NoRelay)
+	if err != nil {
+		common.ResponseError(c, err)
+		return
+	}
+	// relays should update their passages to the server
+	if err = service.ReqSyncPassagesByServer(nil, ticket, false); err != nil {
+		common.ResponseError(c, fmt.Errorf("ReqSyncPassagesByServer: %v", err))
+		return
+	}
+	common.ResponseSuccess(c, gin.H{
+		"Name":    server.Name,
+		"NoRelay": server.NoRelay,
+	})
+}
+
+// PostServerSync forces to sync the passages of the server or relay
+func PostServerSync(c *gin.Context) {
+	var req struct {
+		VerificationCode string
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ResponseBadRequestError(c)
+		return
+	}
+	chatIdentifier := c.Param("ChatIdentifier")
+	if _, err := service.VerifiedAs(nil, req.VerificationCode, chatIdentifier, model.RoleOperator); err != nil {
+		common.ResponseError(c, err)
+		return
+	}
+	ticket := c.Param("Ticket")
+	tic, err := service.GetValidTicketObj(nil, ticket)
+	if err != nil {
+		common.ResponseError(c, err)
+		return
+	}
+	if tic.ChatIdentifier != chatIdentifier {
+		common.ResponseError(c, service.ErrInvalidTicket)
+		return
+	}
+	if _, err = service.GetServerByTicket(nil, ticket); err != nil {
+		common.ResponseError(c, err)
+		return
+	}
+	if err = service.ReqSyncPassagesByServer(nil, ticket, true); err != nil {
+		common.ResponseError(c, fmt.Errorf("ReqSyncPassagesByServer: %v", err))
+		return
+	}
+	common.ResponseSuccess(c, nil)
+}
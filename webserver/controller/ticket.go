@@ -14,6 +14,7 @@ func GetTicket(c *gin.Context) {
 	var query struct {
 		Type             int
 		VerificationCode string
+		QuotaGiB         int64
 	}
 	if err := c.ShouldBindQuery(&query); err != nil ||
 		!model.TicketType(query.Type).IsValid() {
@@ -21,7 +22,13 @@ func GetTicket(c *gin.Context) {
 		return
 	}
 	chatIdentifier := c.Param("ChatIdentifier")
-	if err := service.Verified(nil, query.VerificationCode, chatIdentifier); err != nil {
+	// issuing server and relay tickets is to change the servers of the chat
+	requiredRole := model.RoleMember
+	if model.TicketType(query.Type) != model.TicketTypeUser {
+		requiredRole = model.RoleOperator
+	}
+	verification, err := service.VerifiedAs(nil, query.VerificationCode, chatIdentifier, requiredRole)
+	if err != nil {
 		common.ResponseError(c, err)
 		return
 	}
@@ -31,11 +38,17 @@ func GetTicket(c *gin.Context) {
 		return
 	}
 	// SaveTicket
-	tic, err := service.SaveTicket(nil, ticket, model.TicketType(query.Type), chatIdentifier)
+	tic, err := service.SaveTicket(nil, ticket, model.TicketType(query.Type), chatIdentifier, verification.Member)
 	if err != nil {
 		common.ResponseError(c, err)
 		return
 	}
+	if tic.Type == model.TicketTypeUser && query.QuotaGiB > 0 {
+		if tic, err = service.SetTicketQuota(nil, ticket, chatIdentifier, query.QuotaGiB); err != nil {
+			common.ResponseError(c, err)
+			return
+		}
+	}
 	// ReqSyncPassagesByChatIdentifier
 	if tic.Type == model.TicketTypeUser {
 		if err := service.ReqSyncPassagesByChatIdentifier(nil, chatIdentifier, true); err != nil {
@@ -64,7 +77,8 @@ func PostRenew(c *gin.Context) {
 		return
 	}
 	// verify the VerificationCode
-	if err := service.Verified(nil, req.VerificationCode, ticObj.ChatIdentifier); err != nil {
+	verification, err := service.GetVerified(nil, req.VerificationCode, ticObj.ChatIdentifier)
+	if err != nil {
 		common.ResponseError(c, err)
 		return
 	}
@@ -72,7 +86,7 @@ func PostRenew(c *gin.Context) {
 		common.ResponseBadRequestError(c)
 		return
 	}
-	renewedTic, err := service.SaveTicket(nil, ticket, ticObj.Type, ticObj.ChatIdentifier)
+	renewedTic, err := service.SaveTicket(nil, ticket, ticObj.Type, ticObj.ChatIdentifier, verification.Member)
 	if err != nil {
 		common.ResponseError(c, err)
 		return
@@ -86,3 +100,32 @@ func PostRenew(c *gin.Context) {
 	}
 	common.ResponseSuccess(c, renewedTic)
 }
+
+// PostQuota will set the monthly quota of a user ticket
+func PostQuota(c *gin.Context) {
+	var req struct {
+		VerificationCode string
+		QuotaGiB         int64
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ResponseBadRequestError(c)
+		return
+	}
+	ticket := c.Param("Ticket")
+	ticObj, err := service.GetTicketObj(nil, ticket)
+	if err != nil {
+		common.ResponseError(c, err)
+		return
+	}
+	// verify the VerificationCode. Members should not lift their own quota
+	if _, err := service.VerifiedAs(nil, req.VerificationCode, ticObj.ChatIdentifier, model.RoleOperator); err != nil {
+		common.ResponseError(c, err)
+		return
+	}
+	tic, err := service.SetTicketQuota(nil, ticket, ticObj.ChatIdentifier, req.QuotaGiB)
+	if err != nil {
+		common.ResponseError(c, err)
+		return
+	}
+	common.ResponseSuccess(c, tic)
+}
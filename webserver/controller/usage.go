@@ -0,0 +1,23 @@
+package controller
+
+import (
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/common"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/model"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/service"
+	"github.com/gin-gonic/gin"
+)
+
+// GetUsage returns the monthly traffic usage of the user ticket
+func GetUsage(c *gin.Context) {
+	ticObj := c.MustGet("TicketObj").(*model.Ticket)
+	if ticObj.Type != model.TicketTypeUser {
+		common.ResponseBadRequestError(c)
+		return
+	}
+	usage, err := service.GetTicketUsage(nil, ticObj.Ticket)
+	if err != nil {
+		common.ResponseError(c, err)
+		return
+	}
+	common.ResponseSuccess(c, usage)
+}
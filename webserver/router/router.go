@@ -1,12 +1,15 @@
 package router
 
 import (
+	"crypto/subtle"
 	"embed"
+	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/bot"
 	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/common"
 	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/config"
 	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/service"
 	"github.com/e14914c0-6759-480d-be89-66b7b7676451/SweetLisa/webserver/controller"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"html/template"
 	"io/fs"
 	"net/http"
@@ -24,7 +27,8 @@ func (c relativeFS) Open(name string) (fs.File, error) {
 	return c.root.Open(filepath.Join(c.relativeDir, name))
 }
 
-func Run(f embed.FS) error {
+// Run runs the web server. The telegram updates are received by the webhook if it is not nil.
+func Run(f embed.FS, webhook *bot.Webhook) error {
 	engine := gin.New()
 	templ := template.Must(template.New("").ParseFS(f, "static/*.tmpl"))
 	engine.SetHTMLTemplate(templ)
@@ -59,15 +63,39 @@ func Run(f embed.FS) error {
 		})
 		return nil
 	})
+	metricsHandler := gin.WrapH(promhttp.Handler())
+	engine.GET("/metrics", func(c *gin.Context) {
+		token := config.GetConfig().MetricsToken
+		if token == "" {
+			// labels of metrics reveal chat identifiers, so never export them anonymously
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), []byte("Bearer "+token)) != 1 {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		metricsHandler(c)
+	})
+	if webhook != nil {
+		engine.POST(bot.WebhookPath, gin.WrapH(webhook))
+	}
 	api := engine.Group("api")
 
 	chat := api.Group("chat/:ChatIdentifier")
 	{
 		chat.GET("ticket", controller.GetTicket)
 		chat.GET("verification", controller.GetVerification)
+		chat.POST("setting", controller.PostChatSetting)
+		chat.GET("status", controller.GetChatStatus)
+		chat.GET("servers", controller.GetServers)
+		chat.DELETE("servers/:Ticket", controller.DeleteServer)
+		chat.POST("servers/:Ticket/norelay", controller.PostServerNoRelay)
+		chat.POST("servers/:Ticket/sync", controller.PostServerSync)
 	}
 
 	api.POST("ticket/:Ticket/renew", controller.PostRenew)
+	api.POST("ticket/:Ticket/quota", controller.PostQuota)
 
 	validTicket := api.Group("ticket/:Ticket", func(c *gin.Context) {
 		ticket := c.Param("Ticket")
@@ -83,6 +111,7 @@ func Run(f embed.FS) error {
 	{
 		validTicket.GET("sub", controller.GetSubscription)
 		validTicket.GET("sub/:flags", controller.GetSubscription)
+		validTicket.GET("usage", controller.GetUsage)
 		validTicket.POST("register", controller.PostRegister)
 	}
 	return engine.Run(config.GetConfig().Address)